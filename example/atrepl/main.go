@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/LassiHeikkila/SIM7000/moduleutils"
+)
+
+func main() {
+	deviceFlag := flag.String("device", "/dev/ttyS0", "Which device to talk to module through")
+	timeoutFlag := flag.Duration("timeout", moduleutils.DefaultREPLTimeout, "How long to wait for each command to complete")
+	flag.Parse()
+
+	if err := moduleutils.REPLWithTimeout(*deviceFlag, *timeoutFlag, os.Stdin, os.Stdout); err != nil {
+		log.Println("REPL failed:", err)
+	}
+}
@@ -54,7 +54,11 @@ func main() {
 		output.Println("Failed to create working HTTP client")
 		return
 	}
-	defer httpsClient.Close()
+	defer func() {
+		if err := httpsClient.Close(); err != nil {
+			output.Println("Failed to close HTTP client:", err)
+		}
+	}()
 	/*
 		if err := httpsClient.UploadCert(*certFlag); err != nil {
 			output.Println("Error configuring SSL on module:", err.Error())
@@ -0,0 +1,125 @@
+package https
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"strconv"
+	"time"
+)
+
+// bodyChunkSize is the largest piece of a request body sent to the module at once.
+// Bodies up to this size are sent with a single +SHBOD, larger ones are streamed with +SHBODEXT.
+const bodyChunkSize = 1024
+
+// bodyChunkTimeout is how long the module waits for the data of one +SHBODEXT chunk
+const bodyChunkTimeout = 5 * time.Second
+
+// requestContentLength returns the length of the body of req, -1 if unknown.
+// A Content-Length header set by the caller must agree with req.ContentLength,
+// and gives the length if req.ContentLength doesn't.
+func requestContentLength(req *nethttp.Request) (int64, error) {
+	length := req.ContentLength
+	if req.Body == nil || req.Body == nethttp.NoBody {
+		length = 0
+	} else if length == 0 {
+		length = -1
+	}
+	header := req.Header.Get("Content-Length")
+	if header == "" {
+		return length, nil
+	}
+	declared, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || declared < 0 {
+		return 0, fmt.Errorf("Invalid Content-Length header \"%s\"", header)
+	}
+	if length >= 0 && declared != length {
+		return 0, fmt.Errorf("Content-Length header is %d, but the request body has %d bytes", declared, length)
+	}
+	return declared, nil
+}
+
+// sendBody sends body to the module and closes it.
+// Small bodies are buffered and set with +SHBOD, larger ones are streamed
+// without buffering them fully. If length isn't -1, exactly that many
+// bytes must be available from body.
+func (c *Client) sendBody(rc io.ReadCloser, length int64) error {
+	defer rc.Close()
+
+	var body io.Reader = rc
+	if length >= 0 {
+		body = io.LimitReader(rc, length)
+	}
+	head := make([]byte, bodyChunkSize+1)
+	n, err := io.ReadFull(body, head)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		if length >= 0 && int64(n) != length {
+			return fmt.Errorf("Request body has %d bytes, Content-Length is %d", n, length)
+		}
+		return c.setBody(string(head[:n]))
+	case err != nil:
+		return err
+	}
+
+	sent, err := c.streamBody(io.MultiReader(bytes.NewReader(head[:n]), body))
+	if err != nil {
+		return err
+	}
+	if length >= 0 && sent != length {
+		return fmt.Errorf("Request body has %d bytes, Content-Length is %d", sent, length)
+	}
+	return nil
+}
+
+// streamBody sends everything read from body to the module in +SHBODEXT chunks,
+// returning how many bytes were sent
+func (c *Client) streamBody(body io.Reader) (int64, error) {
+	chunk := make([]byte, bodyChunkSize)
+	var sent int64
+	for {
+		n, err := io.ReadFull(body, chunk)
+		if n > 0 {
+			if err := c.sendBodyChunk(chunk[:n]); err != nil {
+				return sent, err
+			}
+			sent += int64(n)
+		}
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return sent, nil
+		case err != nil:
+			return sent, err
+		}
+	}
+}
+
+// sendBodyChunk appends chunk to the request body stored on the module.
+// The module prompts for the data with "DOWNLOAD" and answers OK once it has received all of it.
+func (c *Client) sendBodyChunk(chunk []byte) error {
+	written := make(chan struct{}, 1)
+	downloadHandler := func([]string) {
+		select {
+		case written <- struct{}{}:
+			c.port.Write(chunk)
+		default:
+		}
+	}
+	if err := c.modem.AddIndication("DOWNLOAD", downloadHandler); err != nil {
+		return err
+	}
+	defer c.modem.CancelIndication("DOWNLOAD")
+
+	cmd := fmt.Sprintf("+SHBODEXT=%d,%d", len(chunk), bodyChunkTimeout.Milliseconds())
+	if err := checkNoErrorAndResponseOK(c.modem.Command(cmd)); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	select {
+	case <-written:
+		return nil
+	default:
+		return errors.New("Module did not prompt for request body data")
+	}
+}
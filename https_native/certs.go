@@ -0,0 +1,188 @@
+package https
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+// DefaultCertName is the name on the module filesystem of the certificate given in Settings.CertPath
+const DefaultCertName = "root.pem"
+
+// maxCertSize is the largest certificate the module accepts
+const maxCertSize = 10240
+
+// serialBaud is the baud rate used for talking to the module
+const serialBaud = 115200
+
+// Bounds of the time given to the module for receiving a file, the upper one is the maximum +CFSWFILE accepts
+const (
+	minUploadTimeout = time.Second
+	maxUploadTimeout = 10 * time.Second
+)
+
+// uploadTimeout returns how long the module is given to receive a file of size bytes at baud:
+// twice the transfer time (10 bits per byte), but at least minUploadTimeout and at most maxUploadTimeout
+func uploadTimeout(size, baud int) time.Duration {
+	timeout := 2 * time.Duration(size*10) * time.Second / time.Duration(baud)
+	if timeout < minUploadTimeout {
+		return minUploadTimeout
+	}
+	if timeout > maxUploadTimeout {
+		return maxUploadTimeout
+	}
+	return timeout
+}
+
+type certContextKey struct{}
+
+// WithCert returns a copy of ctx which makes the Client verify the server of a request
+// made with it against the certificate uploaded as moduleName, see UploadCert
+func WithCert(ctx context.Context, moduleName string) context.Context {
+	return context.WithValue(ctx, certContextKey{}, moduleName)
+}
+
+// UploadCert stores the certificate at localPath on the module filesystem as moduleName,
+// replacing any certificate with the same name, for use with WithCert
+func (c *Client) UploadCert(localPath, moduleName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.uploadCert(localPath, moduleName)
+}
+
+// DeleteCert removes the certificate uploaded as moduleName from the module filesystem
+func (c *Client) DeleteCert(moduleName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.certs[moduleName] {
+		return fmt.Errorf("No certificate uploaded as \"%s\"", moduleName)
+	}
+	if err := checkNoErrorAndResponseOK(c.modem.Command("+CFSINIT")); err != nil {
+		return errors.New("Module filesystem initialization failed")
+	}
+	defer c.modem.Command("+CFSTERM")
+
+	cmd := fmt.Sprintf(`+CFSDFILE=3,"%s"`, moduleName)
+	if err := checkNoErrorAndResponseOK(c.modem.Command(cmd)); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	delete(c.certs, moduleName)
+	if c.certName == moduleName {
+		c.certName = ""
+	}
+	return nil
+}
+
+// certFor returns the name of the certificate to verify the server of req against,
+// empty if it isn't verified. A certificate given with WithCert takes precedence over
+// one given for the host in Settings.CertForHost, which takes precedence over the default.
+func (c *Client) certFor(req *nethttp.Request) (string, error) {
+	name, found := req.Context().Value(certContextKey{}).(string)
+	if !found {
+		name, found = c.hostCert[req.URL.Host]
+	}
+	if !found {
+		name, found = c.hostCert[req.URL.Hostname()]
+	}
+	if !found {
+		return c.certName, nil
+	}
+	if !c.certs[name] {
+		return "", fmt.Errorf("No certificate uploaded as \"%s\"", name)
+	}
+	return name, nil
+}
+
+func (c *Client) uploadCert(certPath, certName string) error {
+	output.Println("Storing certificate on module filesystem")
+	r, err := c.modem.Command("+CFSINIT")
+	if err != nil {
+		return err
+	}
+	ok := false
+	_ = parseResponse_CFSINIT(r, &ok)
+	if !ok {
+		return errors.New("Module filesystem initialization failed")
+	}
+	defer c.modem.Command("+CFSTERM")
+
+	certContents, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return errors.New("Unable to read certificate file: " + err.Error())
+	}
+	if len(certContents) > maxCertSize {
+		return fmt.Errorf(
+			"Certificate is too big (%d bytes) for module filesystem, max allowed is %d",
+			len(certContents),
+			maxCertSize,
+		)
+	}
+	free, err := c.freeFilesystemSpace()
+	if err != nil {
+		return err
+	}
+	if len(certContents) > free {
+		return fmt.Errorf("Module filesystem is full, %d bytes free for a %d byte certificate", free, len(certContents))
+	}
+
+	downloadDone := make(chan struct{})
+	downloadHandler := func([]string) {
+		c.port.Write(certContents)
+		close(downloadDone)
+	}
+	c.modem.AddIndication("DOWNLOAD", downloadHandler)
+	defer c.modem.CancelIndication("DOWNLOAD")
+
+	timeout := uploadTimeout(len(certContents), serialBaud)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := fmt.Sprintf(`+CFSWFILE=%d,"%s",0,%d,%d`, 3, certName, len(certContents), timeout.Milliseconds())
+	if _, err := c.modem.Command(cmd, at.WithTimeout(timeout+time.Second)); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, err)
+	}
+
+	select {
+	case <-downloadDone:
+	case <-ctx.Done():
+		return errors.New("Failed to upload cert")
+	}
+
+	if err := checkNoErrorAndResponseOK(c.modem.Command(fmt.Sprintf(`+CSSLCFG="convert",2,"%s"`, certName))); err != nil {
+		return err
+	}
+	if c.certs == nil {
+		c.certs = make(map[string]bool)
+	}
+	c.certs[certName] = true
+
+	return nil
+}
+
+// freeFilesystemSpace returns the free space on the module filesystem in bytes, from "+CFSGFRS: <free size>"
+func (c *Client) freeFilesystemSpace() (int, error) {
+	r, err := c.modem.Command("+CFSGFRS?")
+	if err != nil {
+		return 0, fmt.Errorf("+CFSGFRS? failed: %w", err)
+	}
+	for _, line := range r {
+		if strings.HasPrefix(line, "+CFSGFRS:") {
+			free, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "+CFSGFRS:")))
+			if err != nil {
+				return 0, fmt.Errorf("Malformed response to +CFSGFRS?: \"%s\"", line)
+			}
+			return free, nil
+		}
+	}
+	return 0, errors.New("Response to +CFSGFRS? did not contain +CFSGFRS:")
+}
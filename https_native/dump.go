@@ -0,0 +1,29 @@
+package https
+
+import (
+	nethttp "net/http"
+
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+// dumpRequest logs the parts of an outgoing request that are sent to the module
+func dumpRequest(req *nethttp.Request) {
+	output.Printf("Request: %s %s\n", req.Method, req.URL)
+	dumpHeader(req.Header)
+	output.Printf("Request body length: %d\n", req.ContentLength)
+}
+
+// dumpResponse logs the parts of a response that were received from the module
+func dumpResponse(resp *nethttp.Response) {
+	output.Printf("Response: %s\n", resp.Status)
+	dumpHeader(resp.Header)
+	output.Printf("Response body length: %d\n", resp.ContentLength)
+}
+
+func dumpHeader(header nethttp.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			output.Printf("  %s: %s\n", key, value)
+		}
+	}
+}
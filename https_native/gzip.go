@@ -0,0 +1,59 @@
+package https
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	nethttp "net/http"
+	"strings"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// decompressBody replaces the body of resp with a decompressing reader if the response is gzip encoded.
+// It is only called when the Client itself asked for gzip.
+// The module does not give us the response headers, so if there is no Content-Encoding header
+// the body is recognized as gzip by its magic bytes.
+func decompressBody(resp *nethttp.Response) error {
+	if resp.Body == nil || resp.Body == nethttp.NoBody {
+		return nil
+	}
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding != "" && !strings.EqualFold(encoding, "gzip") {
+		return nil
+	}
+
+	br := bufio.NewReader(resp.Body)
+	if encoding == "" {
+		if magic, _ := br.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+			resp.Body = &bufferedReadCloser{br, resp.Body}
+			return nil
+		}
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	resp.Body = &gzipReadCloser{zr, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
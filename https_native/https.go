@@ -1,14 +1,15 @@
 package https
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	nethttp "net/http"
-	//"net/http/httputil"
 	"net/url"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	"github.com/warthog618/modem/serial"
 	"github.com/warthog618/modem/trace"
 
+	"github.com/LassiHeikkila/SIM7000/module"
 	"github.com/LassiHeikkila/SIM7000/moduleutils"
 	"github.com/LassiHeikkila/SIM7000/output"
 )
@@ -26,17 +28,39 @@ import (
 type Client struct {
 	modem    *at.AT
 	port     io.ReadWriter
+	reader   *shreadReader
 	mutex    sync.Mutex
 	certName string
+	certs    map[string]bool
+	hostCert map[string]string
+	tlsInfo  TLSInfo
 
 	responseTimeoutDuration time.Duration
 	delayBetweenCmds        time.Duration
+	dumpRequests            bool
+	authorization           string
+	maxResponseBytes        int
+	bodyLen                 int
+	headerLen               int
+
+	progress      readProgress
+	appNetwork    *module.AppNetwork
+	keepAliveStop chan struct{}
+	keepAliveDone chan struct{}
 }
 
 // Settings is a struct used to configure the Client.
 // APN is same APN you would use to configure the Module
 // ProxyIP is http proxy IP to use. None used if empty
 // ProxyPort is http proxy port to use. None used if 0.
+// DumpRequests enables logging of each request and response passing through RoundTrip.
+// MaxResponseBytes limits the size of response bodies, DefaultMaxResponseBytes is used if 0.
+// BodyLen and HeaderLen configure the module's buffers for request body and headers,
+// DefaultBodyLen and DefaultHeaderLen are used if 0.
+// If KeepAliveInterval is set, the application network is checked periodically
+// and reactivated if the module has dropped it, until the Client is closed.
+// CertForHost maps hosts to names of certificates uploaded with UploadCert, which the servers
+// of requests to them are verified against instead of the one at CertPath.
 type Settings struct {
 	APN                   string
 	Username              string
@@ -52,11 +76,28 @@ type Settings struct {
 
 	ResponseTimeoutDuration time.Duration
 	DelayBetweenCommands    time.Duration
+	DumpRequests            bool
+	MaxResponseBytes        int
+	BodyLen                 int
+	HeaderLen               int
+	KeepAliveInterval       time.Duration
+	CertForHost             map[string]string
 }
 
 // DefaultResponseTimeoutDuration is how long to wait for a response from server, by default, after sending a request
 const DefaultResponseTimeoutDuration = 20 * time.Second
 
+// DefaultMaxResponseBytes is the largest response body accepted by default
+const DefaultMaxResponseBytes = 1024 * 1024
+
+// Defaults and maximums for +SHCONF BODYLEN and HEADERLEN
+const (
+	DefaultBodyLen   = 1024
+	MaxBodyLen       = 4096
+	DefaultHeaderLen = 350
+	MaxHeaderLen     = 350
+)
+
 // NewClient returns a ready to use Client, given working Settings.
 // If working Client cannot be created, nil is returned.
 // Client implements net/http RoundTripper for HTTP and HTTPS
@@ -74,8 +115,24 @@ func NewClient(ctx context.Context, settings Settings) *Client {
 		output.Println("You must provide APN to use for HTTP service")
 		return nil
 	}
+	bodyLen := DefaultBodyLen
+	if settings.BodyLen != 0 {
+		bodyLen = settings.BodyLen
+	}
+	if bodyLen < 0 || bodyLen > MaxBodyLen {
+		output.Printf("BodyLen must be between 0 and %d\n", MaxBodyLen)
+		return nil
+	}
+	headerLen := DefaultHeaderLen
+	if settings.HeaderLen != 0 {
+		headerLen = settings.HeaderLen
+	}
+	if headerLen < 0 || headerLen > MaxHeaderLen {
+		output.Printf("HeaderLen must be between 0 and %d\n", MaxHeaderLen)
+		return nil
+	}
 
-	p, err := serial.New(serial.WithPort(settings.SerialPort), serial.WithBaud(115200))
+	p, err := serial.New(serial.WithPort(settings.SerialPort), serial.WithBaud(serialBaud))
 	if err != nil {
 		return nil
 	}
@@ -86,17 +143,20 @@ func NewClient(ctx context.Context, settings Settings) *Client {
 		mio = p
 	}
 
-	modem := at.New(mio, at.WithTimeout(5*time.Second))
+	reader := newSHREADReader(mio)
+	modem := at.New(struct {
+		io.Reader
+		io.Writer
+	}{reader, mio}, at.WithTimeout(5*time.Second))
 
 	if err := modem.Init(at.WithCmds("E0")); err != nil {
 		output.Println("Error initializing modem:", err)
 		return nil
 	}
-	if err := checkNoErrorAndResponseOK(modem.Command("+CFUN=0")); err != nil {
+	if err := module.SetCFUN(modem, module.CFUNMinimum, true); err != nil {
 		output.Println("CFUN=0 not ok:", err)
 		return nil
 	}
-	time.Sleep(5 * time.Second)
 	if err := checkNoErrorAndResponseOK(modem.Command(fmt.Sprintf(`+CGDCONT=1,"IP","%s"`, settings.APN))); err != nil {
 		output.Println("Setting APN not ok:", err)
 		return nil
@@ -118,11 +178,10 @@ func NewClient(ctx context.Context, settings Settings) *Client {
 	}
 	defer modem.CancelIndication(`+CPIN: READY`)
 	output.Println("EXECUTING +CFUN=1")
-	if err := checkNoErrorAndResponseOK(modem.Command("+CFUN=1")); err != nil {
+	if err := module.SetCFUN(modem, module.CFUNFull, true); err != nil {
 		output.Println("CFUN=1 not ok:", err)
 		return nil
 	}
-	time.Sleep(5 * time.Second)
 
 	select {
 	case <-ready:
@@ -131,85 +190,119 @@ func NewClient(ctx context.Context, settings Settings) *Client {
 		return nil
 	}
 	modem.CancelIndication(`+CPIN: READY`)
-	time.Sleep(5 * time.Second)
-	output.Println("EXECUTING +CGATT?")
-	modem.Command("+CGATT?") // "+CGATT: 1"
-	appPdpChan := make(chan struct{})
-	pdpActive := false
-	appPdpHandler := func(s []string) {
-		if s[0] == "+APP PDP: ACTIVE" {
-			pdpActive = true
-		}
-		close(appPdpChan)
-	}
-	err = modem.AddIndication("+APP PDP:", appPdpHandler)
-	if err != nil {
-		output.Println("Failed to add indication for +APP PDP:")
-		return nil
-	}
-	defer modem.CancelIndication(`+APP PDP:`)
-	output.Println("EXECUTING +CNACT=1")
-	if err := checkNoErrorAndResponseOK(modem.Command("+CNACT=1")); err != nil {
-		output.Println("CNACT=1 not ok:", err)
-		return nil
-	}
-	timeout := time.NewTimer(10 * time.Second)
-	defer timeout.Stop()
-	select {
-	case <-ctx.Done():
-		output.Println("Context cancelled!")
-		return nil
-	case <-timeout.C:
-		output.Println("Command +CNACT failed to respond in time")
+	output.Println("Waiting for network attach")
+	if !waitAttached(modem) {
+		output.Println("Network not attached yet, trying to activate anyway")
+	}
+	output.Println("Activating application network")
+	appNetwork := module.NewAppNetwork(modem)
+	if err := appNetwork.Activate(ctx); err != nil {
+		output.Println("Activating application network failed:", err)
 		return nil
-	case <-appPdpChan: // keep going
 	}
-
-	if !pdpActive {
-		output.Println("APP PDP not active")
-		return nil
-	}
-	output.Println("EXECUTING +CNACT?")
-	if err := checkNoErrorAndResponseOK(modem.Command("+CNACT?")); err != nil {
+	_, ip, err := appNetwork.Status()
+	if err != nil {
 		output.Println("CNACT not ok:", err)
 		return nil
 	}
+	output.Println("Application network active with IP", ip)
 	respTimeout := DefaultResponseTimeoutDuration
 	if settings.ResponseTimeoutDuration != 0 {
 		respTimeout = settings.ResponseTimeoutDuration
 	}
+	maxResponseBytes := DefaultMaxResponseBytes
+	if settings.MaxResponseBytes != 0 {
+		maxResponseBytes = settings.MaxResponseBytes
+	}
 	c := &Client{
 		modem:                   modem,
 		port:                    mio,
+		reader:                  reader,
 		responseTimeoutDuration: respTimeout,
 		delayBetweenCmds:        settings.DelayBetweenCommands,
+		dumpRequests:            settings.DumpRequests,
+		maxResponseBytes:        maxResponseBytes,
+		bodyLen:                 bodyLen,
+		headerLen:               headerLen,
+		appNetwork:              appNetwork,
+		hostCert:                make(map[string]string, len(settings.CertForHost)),
+	}
+	// copied so that the caller changing the map doesn't race with requests
+	for host, name := range settings.CertForHost {
+		c.hostCert[host] = name
 	}
 	if settings.CertPath != "" {
-		err := c.uploadCert(settings.CertPath)
+		err := c.uploadCert(settings.CertPath, DefaultCertName)
 		if err != nil {
 			output.Println("Failed to upload certificate!")
 			return nil
 		}
+		c.certName = DefaultCertName
+	}
+	if settings.KeepAliveInterval > 0 {
+		c.startKeepAlive(settings.KeepAliveInterval)
 	}
 
 	return c
 }
 
-// Close shuts down any open https connections
-func (c *Client) Close() {
+// Close shuts down any open https connection and deactivates the application network.
+// Teardown continues past failures, the first one is returned.
+func (c *Client) Close() error {
+	c.stopKeepAlive()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	output.Println("Closing HTTP service")
-	r, err := c.modem.Command("+SHDISC")
+	err := c.disconnect()
 	if err != nil {
-		output.Println("Error executing +SHDISC")
-		return
+		output.Println(err)
 	}
-	ok := false
-	_ = parseResponse_SHDISC(r, &ok)
-	if !ok {
-		output.Println("+SHDISC failed")
-		return
+	if c.appNetwork != nil {
+		if deactivateErr := c.appNetwork.Deactivate(); deactivateErr != nil {
+			output.Println(deactivateErr)
+			if err == nil {
+				err = deactivateErr
+			}
+		}
+	}
+	if err == nil {
+		output.Println("HTTP service terminated with success")
 	}
-	output.Println("HTTP service terminated with success")
+	return err
+}
+
+// disconnect closes the HTTP(S) connection with +SHDISC, if one is open
+func (c *Client) disconnect() error {
+	r, err := c.modem.Command("+SHSTATE?")
+	if err != nil {
+		return fmt.Errorf("+SHSTATE? failed: %w", err)
+	}
+	state := -1
+	_ = parseResponse_SHSTATE_READ(r, &state)
+	if state != 1 {
+		return nil
+	}
+	if _, err := c.modem.Command("+SHDISC"); err != nil {
+		return fmt.Errorf("+SHDISC failed: %w", err)
+	}
+	return nil
+}
+
+// SetBasicAuth makes the Client send the given credentials with every request
+// in an Authorization header, unless the request already has one
+func (c *Client) SetBasicAuth(username, password string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// SetBearerToken makes the Client send the given token with every request
+// in an Authorization header, unless the request already has one
+func (c *Client) SetBearerToken(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.authorization = "Bearer " + token
 }
 
 func (c *Client) wait() {
@@ -218,8 +311,55 @@ func (c *Client) wait() {
 	}
 }
 
-// RoundTrip executes a http request and returns the response
+// Polling of the module while waiting for it to reach a state
+var (
+	pollInterval      = 200 * time.Millisecond
+	shstateTimeout    = 5 * time.Second
+	attachPollTimeout = 10 * time.Second
+)
+
+// waitConnected polls +SHSTATE? until the HTTP connection is reported connected
+func (c *Client) waitConnected() error {
+	deadline := time.Now().Add(shstateTimeout)
+	for {
+		r, err := c.modem.Command("+SHSTATE?")
+		if err != nil {
+			return errors.New("+SHSTATE? returned: " + err.Error())
+		}
+		state := -1
+		_ = parseResponse_SHSTATE_READ(r, &state)
+		if state == 1 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("HTTP connection status is not \"connected\"")
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// waitAttached polls +CGATT? until the module reports being attached to the packet domain,
+// returning false if it isn't attached within attachPollTimeout
+func waitAttached(modem *at.AT) bool {
+	deadline := time.Now().Add(attachPollTimeout)
+	for {
+		r, err := modem.Command("+CGATT?")
+		if err == nil && len(r) > 0 && strings.TrimSpace(r[0]) == "+CGATT: 1" {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// RoundTrip executes a http request and returns the response.
+// The module handles one request at a time, so concurrent calls are serialized.
 func (c *Client) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	switch req.URL.Scheme {
 	case "http":
 		return c.roundTrip(req)
@@ -231,18 +371,23 @@ func (c *Client) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 }
 
 func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
-	//d, _ := httputil.DumpRequest(req, true)
-	//output.Println("Request:\n", string(d))
+	if c.dumpRequests {
+		dumpRequest(req)
+	}
+	contentLength, err := requestContentLength(req)
+	if err != nil {
+		return nil, err
+	}
 	u := fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host)
 	if err := c.configure("URL", u); err != nil {
 		return nil, err
 	}
 	c.wait()
-	if err := c.configure("BODYLEN", 1024); err != nil {
+	if err := c.configure("BODYLEN", c.bodyLen); err != nil {
 		return nil, err
 	}
 	c.wait()
-	if err := c.configure("HEADERLEN", 350); err != nil {
+	if err := c.configure("HEADERLEN", c.headerLen); err != nil {
 		return nil, err
 	}
 	c.wait()
@@ -257,16 +402,9 @@ func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 		return nil, errors.New("Failed to connect with HTTP")
 	}
 	defer c.modem.Command("+SHDISC")
-	time.Sleep(time.Second)
 
-	r, err = c.modem.Command("+SHSTATE?")
-	if err != nil {
-		return nil, errors.New("+SHSTATE? returned: " + err.Error())
-	}
-	state := -1
-	_ = parseResponse_SHSTATE_READ(r, &state)
-	if state != 1 {
-		return nil, errors.New("HTTP connection status is not \"connected\"")
+	if err := c.waitConnected(); err != nil {
+		return nil, err
 	}
 	c.wait()
 
@@ -281,23 +419,40 @@ func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 	}
 	c.wait()
 
+	// each value is added as a header line of its own, as joining them with ","
+	// would break values which contain commas. Cookie is the exception, RFC 6265
+	// allows a single Cookie header, with the cookies separated by "; ".
+	// Keys are used as they are in the map, so casing set without Header.Set is preserved.
 	for key, values := range req.Header {
-		v := strings.Join(values, ",")
-		err := c.setHeader(key, v)
-		if err != nil {
+		if nethttp.CanonicalHeaderKey(key) == "Cookie" {
+			values = []string{strings.Join(values, "; ")}
+		}
+		for _, v := range values {
+			if err := c.setHeader(key, v); err != nil {
+				return nil, err
+			}
+			c.wait()
+		}
+	}
+	if c.authorization != "" && req.Header.Get("Authorization") == "" {
+		if err := c.setHeader("Authorization", c.authorization); err != nil {
 			return nil, err
 		}
 		c.wait()
 	}
-
-	if req.Body != nil {
-		b, err := ioutil.ReadAll(req.Body)
-		if err != nil {
+	// like net/http, gzip is requested unless the caller chose the encoding,
+	// and only then is the response decompressed transparently
+	requestedGzip := false
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != nethttp.MethodHead {
+		if err := c.setHeader("Accept-Encoding", "gzip"); err != nil {
 			return nil, err
 		}
-		req.Body.Close()
-		err = c.setBody(string(b))
-		if err != nil {
+		c.wait()
+		requestedGzip = true
+	}
+
+	if req.Body != nil {
+		if err := c.sendBody(req.Body, contentLength); err != nil {
 			return nil, err
 		}
 		c.wait()
@@ -344,29 +499,51 @@ func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 		return nil, shreqErr
 	}
 
-	dataRead := 0
-	responseData := ""
-	allReadChan := make(chan struct{})
-	if dataLen > 0 {
-		readIndicationHandler := func(r []string) {
-			var length int
-			var data string
-			parseResponse_SHREAD_UNSOLICITED_RESPONSE(r, &data, &length)
-			dataRead += length
-			responseData += data
-
-			if dataRead >= dataLen {
-				close(allReadChan)
-			}
-		}
+	// a response to HEAD has no body, so its reported length isn't limited
+	if dataLen > c.maxResponseBytes && req.Method != nethttp.MethodHead {
+		return nil, fmt.Errorf("Response body of %d bytes exceeds limit of %d bytes", dataLen, c.maxResponseBytes)
+	}
 
-		err = c.modem.AddIndication("+SHREAD:", readIndicationHandler)
+	c.progress.start(dataLen)
+	defer c.progress.start(0)
+	var responseData []byte
+	var readErr error
+	var readMutex sync.Mutex
+	readDone := false
+	allReadChan := make(chan struct{})
+	// a response to HEAD has no body even though the module reports the length of the content
+	if dataLen > 0 && req.Method != nethttp.MethodHead {
+		// the body data following each "+SHREAD: <len>" line is taken off the stream by c.reader,
+		// the header lines themselves are consumed by this indication
+		err = c.modem.AddIndication("+SHREAD:", func([]string) {})
 		if err != nil {
 			log.Println("error registering read indication handler:", err)
 			return nil, err
 		}
 		defer c.modem.CancelIndication("+SHREAD:")
 
+		c.reader.divert(func(data []byte) {
+			readMutex.Lock()
+			defer readMutex.Unlock()
+			if readDone {
+				return
+			}
+			responseData = append(responseData, data...)
+			c.progress.consume(len(data))
+
+			// the module may send more than the length it reported
+			if len(responseData) > c.maxResponseBytes {
+				readErr = fmt.Errorf("Response body exceeds limit of %d bytes", c.maxResponseBytes)
+				responseData = nil
+				readDone = true
+				close(allReadChan)
+			} else if len(responseData) >= dataLen {
+				readDone = true
+				close(allReadChan)
+			}
+		})
+		defer c.reader.divert(nil)
+
 		_, err := c.modem.Command(fmt.Sprintf(`+SHREAD=0,%d`, dataLen))
 		if err != nil {
 			return nil, err
@@ -375,18 +552,25 @@ func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 	} else {
 		close(allReadChan)
 	}
+	readTimeout := time.NewTimer(c.responseTimeoutDuration)
+	defer readTimeout.Stop()
 	select {
 	case <-allReadChan:
+	case <-readTimeout.C:
+		return nil, errors.New("Timed out reading response body")
 	case <-req.Context().Done():
 		return nil, errors.New("context done")
 	}
+	readMutex.Lock()
+	defer readMutex.Unlock()
+	if readErr != nil {
+		return nil, readErr
+	}
 
-	var respReadCloser io.ReadCloser
+	// like net/http, Body is never nil
+	var respReadCloser io.ReadCloser = nethttp.NoBody
 	if len(responseData) > 0 {
-		respReader := strings.NewReader(responseData)
-		respReadCloser = ioutil.NopCloser(respReader)
-	} else {
-		respReadCloser = nil
+		respReadCloser = ioutil.NopCloser(bytes.NewReader(responseData))
 	}
 
 	resp := &nethttp.Response{
@@ -396,25 +580,43 @@ func (c *Client) roundTrip(req *nethttp.Request) (*nethttp.Response, error) {
 		ProtoMajor:    1,
 		ProtoMinor:    1,
 		Body:          respReadCloser,
+		Header:        make(nethttp.Header),
 		ContentLength: int64(dataLen),
 		Request:       req,
 	}
+	if requestedGzip {
+		if err := decompressBody(resp); err != nil {
+			return nil, err
+		}
+	}
+	if c.dumpRequests {
+		dumpResponse(resp)
+	}
 
 	return resp, nil
 }
 
 func (c *Client) roundTripHTTPS(req *nethttp.Request) (*nethttp.Response, error) {
-	if err := checkNoErrorAndResponseOK(c.modem.Command(`+CSSLCFG="sslversion",1,3`)); err != nil {
+	if err := checkNoErrorAndResponseOK(c.modem.Command(sslVersionCommand())); err != nil {
 		return nil, err
 	}
 	c.wait()
+	certName, err := c.certFor(req)
+	if err != nil {
+		return nil, err
+	}
 	// empty certName means server cert is not verified
-	if err := checkNoErrorAndResponseOK(c.modem.Command(fmt.Sprintf(`+SHSSL=1,"%s"`, c.certName))); err != nil {
+	if err := checkNoErrorAndResponseOK(c.modem.Command(fmt.Sprintf(`+SHSSL=%d,"%s"`, sslContext, certName))); err != nil {
 		return nil, err
 	}
 	c.wait()
 
-	return c.roundTrip(req)
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	c.tlsInfo = c.querySSLStatus(certName)
+	return resp, nil
 }
 
 func (c *Client) configure(key string, value interface{}) error {
@@ -431,9 +633,12 @@ func (c *Client) configure(key string, value interface{}) error {
 }
 
 func (c *Client) setHeader(key, value string) error {
+	if err := validateHeader(key, value); err != nil {
+		return err
+	}
 	var r []string
 	var err error
-	if r, err = c.modem.Command(fmt.Sprintf(`+SHAHEAD="%s","%s"`, key, value)); err != nil {
+	if r, err = c.modem.Command(fmt.Sprintf(`+SHAHEAD="%s","%s"`, key, strings.ReplaceAll(value, `"`, `\"`))); err != nil {
 		return errors.New("+SHAHEAD returned ERROR")
 	}
 	ok := false
@@ -444,6 +649,24 @@ func (c *Client) setHeader(key, value string) error {
 	return nil
 }
 
+// validateHeader rejects headers which would corrupt the +SHAHEAD command or inject extra headers
+func validateHeader(key, value string) error {
+	if key == "" {
+		return errors.New("Empty header name")
+	}
+	for _, r := range key {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return fmt.Errorf("Invalid character %q in header name \"%s\"", r, key)
+		}
+	}
+	for _, r := range value {
+		if (r < ' ' && r != '\t') || r == 0x7f {
+			return fmt.Errorf("Invalid character %q in value of header \"%s\"", r, key)
+		}
+	}
+	return nil
+}
+
 func (c *Client) setParameter(key, value string) error {
 	var r []string
 	var err error
@@ -490,7 +713,7 @@ func (c *Client) executeRequest(method string, url url.URL) error {
 		return errors.New("Method not supported by SIM7000X: " + method)
 	}
 
-	r, err := c.modem.Command(fmt.Sprintf(`+SHREQ="%s",%d`, url.RequestURI(), methodInt))
+	r, err := c.modem.Command(fmt.Sprintf(`+SHREQ="%s",%d`, requestURI(url), methodInt))
 	if err != nil {
 		return err
 	}
@@ -502,65 +725,27 @@ func (c *Client) executeRequest(method string, url url.URL) error {
 	return nil
 }
 
-func (c *Client) uploadCert(certPath string) error {
-	output.Println("Storing certificate on module filesystem")
-	r, err := c.modem.Command("+CFSINIT")
-	if err != nil {
-		return err
-	}
-	ok := false
-	_ = parseResponse_CFSINIT(r, &ok)
-	if !ok {
-		return errors.New("Module filesystem initialization failed")
-	}
-	defer c.modem.Command("+CFSTERM")
-
-	const maxFileSize = 10240
-	const timeoutMs = 1000
-	certContents, err := ioutil.ReadFile(certPath)
-	certName := "root.pem"
-	if err != nil {
-		return errors.New("Unable to read certificate file: " + err.Error())
-	}
-	if len(certContents) > maxFileSize {
-		return fmt.Errorf(
-			"Certificate is too big (%d bytes) for module filesystem, max allowed is %d",
-			len(certContents),
-			maxFileSize,
-		)
-	}
-	downloadDone := make(chan struct{})
-	downloadHandler := func([]string) {
-		c.port.Write(certContents)
-		close(downloadDone)
-	}
-	c.modem.AddIndication("DOWNLOAD", downloadHandler)
-	defer c.modem.CancelIndication("DOWNLOAD")
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutMs*time.Millisecond)
-	defer cancel()
-
-	c.modem.Command(
-		fmt.Sprintf(
-			`+CFSWFILE=%d,"%s",0,%d,%d`,
-			3,
-			certName,
-			len(certContents),
-			timeoutMs))
-
-	select {
-	case <-downloadDone:
-	case <-ctx.Done():
-		return errors.New("Failed to upload cert")
-	}
-
-	c.certName = certName
+// requestURI returns the path and query of u, with the characters of the query which aren't allowed
+// in a URL, e.g. spaces, quotes and non-ASCII characters, percent-encoded so they can't break the AT command
+func requestURI(u url.URL) string {
+	u.RawQuery = escapeQuery(u.RawQuery)
+	return u.RequestURI()
+}
 
-	if err := checkNoErrorAndResponseOK(c.modem.Command(fmt.Sprintf(`+CSSLCFG="convert",2,"%s"`, c.certName))); err != nil {
-		return err
+// escapeQuery percent-encodes the bytes of rawQuery which aren't allowed in a URL query,
+// keeping its structure and any existing escapes
+func escapeQuery(rawQuery string) string {
+	var b strings.Builder
+	for i := 0; i < len(rawQuery); i++ {
+		c := rawQuery[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			strings.IndexByte("-._~!$&'()*+,;=:@/?%", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
 	}
-
-	return nil
+	return b.String()
 }
 
 type method int8
@@ -0,0 +1,1007 @@
+package https
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	nethttp "net/http"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/internal/fakemodem"
+	"github.com/LassiHeikkila/SIM7000/module"
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+func newTestClient(m *fakemodem.Modem) *Client {
+	reader := newSHREADReader(m)
+	return &Client{
+		modem: at.New(struct {
+			io.Reader
+			io.Writer
+		}{reader, m}, at.WithTimeout(time.Second)),
+		port:                    m,
+		reader:                  reader,
+		responseTimeoutDuration: time.Second,
+		maxResponseBytes:        DefaultMaxResponseBytes,
+		bodyLen:                 DefaultBodyLen,
+		headerLen:               DefaultHeaderLen,
+	}
+}
+
+func newTestModem() *fakemodem.Modem {
+	m := fakemodem.New()
+	m.Reply("+SHSTATE?", "+SHSTATE: 1\nOK")
+	return m
+}
+
+// newTestSetup returns a fake modem answering +SHSTATE? as connected and a Client using it.
+// Output is discarded and the modem closed when the test ends.
+func newTestSetup(t *testing.T) (*fakemodem.Modem, *Client) {
+	m := newTestModem()
+	output.SetWriter(ioutil.Discard)
+	t.Cleanup(func() {
+		output.SetWriter(ioutil.Discard)
+		m.Close()
+	})
+	return m, newTestClient(m)
+}
+
+func TestRoundTripDumpsRequestAndResponse(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/status",1`, "OK\n\n+SHREQ: \"GET\",204,0")
+
+	c.dumpRequests = true
+
+	var buf bytes.Buffer
+	output.SetWriter(&buf)
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/status", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf(`Got status %d, wanted 204`, resp.StatusCode)
+	}
+
+	dump := buf.String()
+	for _, want := range []string{
+		"Request: GET http://example.com/status",
+		"Accept: application/json",
+		"Request body length: 0",
+		"Response: 204 No Content",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf(`Dump %q does not contain %q`, dump, want)
+		}
+	}
+}
+
+// shreadData returns what the module sends after +SHREAD, each chunk preceded by a "+SHREAD: <len>" line
+func shreadData(chunks ...string) string {
+	data := ""
+	for _, chunk := range chunks {
+		data += fmt.Sprintf("\r\n+SHREAD: %d\r\n%s\r\n", len(chunk), chunk)
+	}
+	return data
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressBody(t *testing.T) {
+	const body = `{"hello":"world"}`
+	tests := map[string]struct {
+		contentEncoding string
+		data            []byte
+		want            string
+		uncompressed    bool
+	}{
+		"gzip detected from magic": {
+			data:         gzipped(t, body),
+			want:         body,
+			uncompressed: true,
+		},
+		"gzip from Content-Encoding": {
+			contentEncoding: "gzip",
+			data:            gzipped(t, body),
+			want:            body,
+			uncompressed:    true,
+		},
+		"plain body": {
+			data: []byte(body),
+			want: body,
+		},
+		"other Content-Encoding": {
+			contentEncoding: "br",
+			data:            []byte(body),
+			want:            body,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := &nethttp.Response{
+				Header:        make(nethttp.Header),
+				Body:          ioutil.NopCloser(bytes.NewReader(tc.data)),
+				ContentLength: int64(len(tc.data)),
+			}
+			if tc.contentEncoding != "" {
+				resp.Header.Set("Content-Encoding", tc.contentEncoding)
+			}
+			if err := decompressBody(resp); err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			got, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf(`Unexpected error reading body: %v`, err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+			if resp.Uncompressed != tc.uncompressed {
+				t.Fatalf(`Got Uncompressed %v, wanted %v`, resp.Uncompressed, tc.uncompressed)
+			}
+			if tc.uncompressed && resp.Header.Get("Content-Encoding") != "" {
+				t.Fatal(`Content-Encoding header was not removed`)
+			}
+		})
+	}
+}
+
+func TestRoundTripDecompressesGzipBody(t *testing.T) {
+	const body = "{\"hello\":\"world\"}\n"
+	data := gzipped(t, body)
+	tests := map[string]struct {
+		acceptEncoding string
+		want           string
+		uncompressed   bool
+	}{
+		"gzip requested by the client": {
+			want:         body,
+			uncompressed: true,
+		},
+		"encoding chosen by the caller": {
+			acceptEncoding: "gzip",
+			want:           string(data),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestSetup(t)
+			m.Reply(`+SHREQ="/",1`, fmt.Sprintf("OK\n\n+SHREQ: \"GET\",200,%d", len(data)))
+			m.ReplyData(fmt.Sprintf("+SHREAD=0,%d", len(data)), shreadData(string(data)))
+
+			req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			resp, err := c.RoundTrip(req)
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			got, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf(`Unexpected error reading body: %v`, err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf(`Got body %q, wanted %q`, got, tc.want)
+			}
+			if resp.Uncompressed != tc.uncompressed {
+				t.Fatalf(`Got Uncompressed %v, wanted %v`, resp.Uncompressed, tc.uncompressed)
+			}
+			requested := containsCommand(m.Commands(), `+SHAHEAD="Accept-Encoding","gzip"`)
+			if requested != (tc.acceptEncoding == "" || tc.acceptEncoding == "gzip") {
+				t.Fatalf(`Got commands %q`, m.Commands())
+			}
+		})
+	}
+}
+
+func TestRoundTripSendsMultiValuedHeaderSeparately(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+	req.Header.Add("Cookie", "a=1, 2")
+	req.Header.Add("Cookie", "b=3")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	req.Header["x-lowercase"] = []string{"kept"}
+	if _, err := c.RoundTrip(req); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+
+	got := make([]string, 0)
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+SHAHEAD=") {
+			got = append(got, cmd)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		`+SHAHEAD="Accept-Encoding","gzip"`,
+		`+SHAHEAD="Accept","text/html"`,
+		`+SHAHEAD="Accept","application/json"`,
+		`+SHAHEAD="Cookie","a=1, 2; b=3"`,
+		`+SHAHEAD="x-lowercase","kept"`,
+	}
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf(`Got %q, wanted %q`, got, want)
+	}
+	if len(req.Header["Cookie"]) != 2 {
+		t.Fatalf(`Request headers were modified: %v`, req.Header)
+	}
+}
+
+func TestSetHeaderEscaping(t *testing.T) {
+	m, c := newTestSetup(t)
+
+	if err := c.setHeader("If-None-Match", `"abc"`); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := `+SHAHEAD="If-None-Match","\"abc\""`
+	if got := m.Commands(); len(got) != 1 || got[0] != want {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+
+	tests := map[string]struct {
+		key   string
+		value string
+	}{
+		"newline in value":        {key: "X-Test", value: "a\"b\r\nInjected: yes"},
+		"quote in name":           {key: `X-"Test`, value: "a"},
+		"control character name":  {key: "X-Test\n", value: "a"},
+		"empty name":              {key: "", value: "a"},
+		"null character in value": {key: "X-Test", value: "a\x00"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := c.setHeader(tc.key, tc.value); err == nil {
+				t.Fatal(`Expected an error`)
+			}
+		})
+	}
+	if got := m.Commands(); len(got) != 1 {
+		t.Fatalf(`Invalid headers were sent to the module: %q`, got[1:])
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeRecorder) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestNewMultipartRequestBodyClosedUnread(t *testing.T) {
+	file := &closeRecorder{Reader: strings.NewReader("data"), closed: make(chan struct{})}
+	req, err := NewMultipartRequest(
+		"https://example.com/upload",
+		map[string]string{"device": "sensor-1"},
+		[]FilePart{{FieldName: "log", FileName: "log.txt", Content: file}},
+	)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	req.Body.Close()
+
+	select {
+	case <-file.closed:
+	case <-time.After(time.Second):
+		t.Fatal(`Body writer did not stop and close the file after the body was closed`)
+	}
+}
+
+func TestNewMultipartRequest(t *testing.T) {
+	req, err := NewMultipartRequest(
+		"https://example.com/upload",
+		map[string]string{"device": "sensor-1", "battery": "87"},
+		[]FilePart{{FieldName: "photo", FileName: "photo.jpg", Content: strings.NewReader("\xff\xd8jpegdata")}},
+	)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if req.Method != nethttp.MethodPost {
+		t.Fatalf(`Got method %s, wanted POST`, req.Method)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		t.Fatalf(`Unexpected Content-Type %q`, req.Header.Get("Content-Type"))
+	}
+
+	type part struct{ name, fileName, content string }
+	want := []part{
+		{name: "battery", content: "87"},
+		{name: "device", content: "sensor-1"},
+		{name: "photo", fileName: "photo.jpg", content: "\xff\xd8jpegdata"},
+	}
+	mr := multipart.NewReader(req.Body, params["boundary"])
+	for i := range want {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf(`Part %d: unexpected error: %v`, i, err)
+		}
+		content, _ := ioutil.ReadAll(p)
+		got := part{name: p.FormName(), fileName: p.FileName(), content: string(content)}
+		if got != want[i] {
+			t.Errorf(`Part %d: got %+v, wanted %+v`, i, got, want[i])
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf(`Expected end of body, got %v`, err)
+	}
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(c *Client)
+		request string
+		want    string
+	}{
+		"basic auth": {
+			setup: func(c *Client) { c.SetBasicAuth("user", "pass") },
+			want:  `+SHAHEAD="Authorization","Basic dXNlcjpwYXNz"`,
+		},
+		"bearer token": {
+			setup: func(c *Client) { c.SetBearerToken("abc123") },
+			want:  `+SHAHEAD="Authorization","Bearer abc123"`,
+		},
+		"request header takes precedence": {
+			setup:   func(c *Client) { c.SetBasicAuth("user", "pass") },
+			request: "Bearer fromrequest",
+			want:    `+SHAHEAD="Authorization","Bearer fromrequest"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestSetup(t)
+			m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+			tc.setup(c)
+
+			req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+			if tc.request != "" {
+				req.Header.Set("Authorization", tc.request)
+			}
+			if _, err := c.RoundTrip(req); err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+
+			var got []string
+			for _, cmd := range m.Commands() {
+				if strings.HasPrefix(cmd, `+SHAHEAD="Authorization"`) {
+					got = append(got, cmd)
+				}
+			}
+			if len(got) != 1 || got[0] != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetAuthDuringRoundTrip(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+		c.RoundTrip(req)
+	}()
+	c.SetBearerToken("abc123")
+	c.SetBasicAuth("user", "pass")
+	<-done
+}
+
+func TestRoundTripResponseSizeLimit(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/big",1`, "OK\n\n+SHREQ: \"GET\",200,2048")
+	c.maxResponseBytes = 1024
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/big", nil)
+	if _, err := c.RoundTrip(req); err == nil {
+		t.Fatal(`Expected an error for a response exceeding the limit`)
+	}
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+SHREAD") {
+			t.Fatalf(`Response body was read despite exceeding the limit`)
+		}
+	}
+}
+
+func TestRoundTripResponseSizeLimitWhileReading(t *testing.T) {
+	m, c := newTestSetup(t)
+	// the reported length is within the limit, but the module sends more data than that
+	m.Reply(`+SHREQ="/growing",1`, "OK\n\n+SHREQ: \"GET\",200,1024")
+	m.ReplyData(`+SHREAD=0,1024`, shreadData(strings.Repeat("a", 512), strings.Repeat("b", 1000)))
+	c.maxResponseBytes = 1024
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/growing", nil)
+	resp, err := c.RoundTrip(req)
+	if resp != nil {
+		t.Fatalf(`Got a response with %d bytes of body over the limit`, resp.ContentLength)
+	}
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Fatalf(`Got %v, wanted an error for data exceeding the limit`, err)
+	}
+	reads := 0
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+SHREAD") {
+			reads++
+		}
+	}
+	if reads != 1 {
+		t.Fatalf(`Got %d +SHREAD commands, wanted reading to stop after the first`, reads)
+	}
+	if got := c.RemainingBytes(); got != 0 {
+		t.Fatalf(`Got %d remaining bytes after aborting the read, wanted 0`, got)
+	}
+}
+
+func TestRoundTripResponseWithinLimit(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/small",1`, "OK\n\n+SHREQ: \"GET\",200,5")
+	m.ReplyData(`+SHREAD=0,5`, shreadData("hello"))
+	c.maxResponseBytes = 1024
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/small", nil)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.ContentLength != 5 {
+		t.Fatalf(`Got content length %d, wanted 5`, resp.ContentLength)
+	}
+	if body, err := ioutil.ReadAll(resp.Body); err != nil || string(body) != "hello" {
+		t.Fatalf(`Got body %q, %v, wanted "hello"`, body, err)
+	}
+}
+
+func TestRoundTripEmptyResponseHasBody(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",204,0")
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.Body != nethttp.NoBody {
+		t.Fatalf(`Got body %v, wanted NoBody`, resp.Body)
+	}
+}
+
+func TestRoundTripConfiguresBodyAndHeaderLength(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+	c.bodyLen = 4096
+	c.headerLen = 200
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/", nil)
+	if _, err := c.RoundTrip(req); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	cmds := strings.Join(m.Commands(), "\n")
+	for _, want := range []string{`+SHCONF="BODYLEN",4096`, `+SHCONF="HEADERLEN",200`} {
+		if !strings.Contains(cmds, want) {
+			t.Errorf(`Commands %q do not contain %q`, cmds, want)
+		}
+	}
+}
+
+func TestRoundTripHeadDoesNotReadBody(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/file",5`, "OK\n\n+SHREQ: \"HEAD\",200,5000000")
+
+	req, _ := nethttp.NewRequest(nethttp.MethodHead, "http://example.com/file", nil)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	// larger than maxResponseBytes, which only limits bodies that are read
+	if resp.StatusCode != 200 || resp.ContentLength != 5000000 {
+		t.Fatalf(`Got status %d and length %d, wanted 200 and 5000000`, resp.StatusCode, resp.ContentLength)
+	}
+	if resp.Body != nethttp.NoBody {
+		t.Fatal(`Expected an empty body`)
+	}
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+SHREAD") {
+			t.Fatal(`+SHREAD issued for HEAD request`)
+		}
+	}
+}
+
+func TestRoundTripWaitsForConnection(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply("+SHSTATE?", "+SHSTATE: 0\nOK", "+SHSTATE: 1\nOK")
+	m.Reply(`+SHREQ="/status",1`, "OK\n\n+SHREQ: \"GET\",204,0")
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/status", nil)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf(`Got status %d, wanted 204`, resp.StatusCode)
+	}
+	polls := 0
+	for _, cmd := range m.Commands() {
+		if cmd == "+SHSTATE?" {
+			polls++
+		}
+	}
+	if polls != 2 {
+		t.Fatalf(`Got %d +SHSTATE? polls, wanted 2`, polls)
+	}
+}
+
+func TestKeepAliveReactivatesAppNetwork(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply("+CNACT?", `+CNACT: 0,"0.0.0.0"`+"\nOK", `+CNACT: 0,"0.0.0.0"`+"\nOK", `+CNACT: 1,"10.170.42.7"`+"\nOK")
+	m.Reply("+CNACT=1", "OK\n\n+APP PDP: ACTIVE")
+
+	c.appNetwork = module.NewAppNetwork(c.modem)
+	c.startKeepAlive(20 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	c.stopKeepAlive()
+
+	activations := 0
+	for _, cmd := range m.Commands() {
+		if cmd == "+CNACT=1" {
+			activations++
+		}
+	}
+	if activations != 1 {
+		t.Fatalf(`Got %d activations, wanted 1: %q`, activations, m.Commands())
+	}
+}
+
+func TestRemainingBytesAcrossChunks(t *testing.T) {
+	var p readProgress
+	p.start(10)
+	for _, step := range []struct{ read, remaining int }{{4, 6}, {3, 3}, {3, 0}} {
+		if got := p.consume(step.read); got != step.remaining || p.get() != step.remaining {
+			t.Fatalf(`Got %d remaining after reading %d, wanted %d`, got, step.read, step.remaining)
+		}
+	}
+
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/chunked",1`, "OK\n\n+SHREQ: \"GET\",200,10")
+	// the rest of the body is sent once the first chunk has been counted
+	m.ReplyData(`+SHREAD=0,10`, shreadData("ab\r\n"))
+
+	type result struct {
+		resp *nethttp.Response
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/chunked", nil)
+		resp, err := c.RoundTrip(req)
+		done <- result{resp, err}
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for c.RemainingBytes() != 6 {
+		if time.Now().After(deadline) {
+			t.Fatalf(`Got %d remaining bytes after the first chunk, wanted 6`, c.RemainingBytes())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	m.Inject("+SHREAD: 6\nefghij")
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf(`Unexpected error: %v`, r.err)
+	}
+	if body, _ := ioutil.ReadAll(r.resp.Body); string(body) != "ab\r\nefghij" {
+		t.Fatalf(`Got body %q, wanted "ab\r\nefghij"`, body)
+	}
+	if got := c.RemainingBytes(); got != 0 {
+		t.Fatalf(`Got %d remaining bytes after reading the whole body, wanted 0`, got)
+	}
+}
+
+func TestRoundTripStreamsLargeBody(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/upload",3`, "OK\n\n+SHREQ: \"POST\",201,0")
+	m.Reply("+SHBODEXT=1024,5000", "DOWNLOAD")
+	m.Reply("+SHBODEXT=952,5000", "DOWNLOAD")
+	m.ReplyRaw("OK")
+
+	body := bytes.Repeat([]byte("0123456789abcdef"), 3000/16+1)[:3000]
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < len(body); i += 100 {
+			pw.Write(body[i : i+100])
+		}
+		pw.Close()
+	}()
+
+	req, _ := nethttp.NewRequest(nethttp.MethodPost, "http://example.com/upload", pr)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.StatusCode != 201 {
+		t.Fatalf(`Got status %d, wanted 201`, resp.StatusCode)
+	}
+	if !bytes.Equal(m.Raw(), body) {
+		t.Fatalf(`Module received %d bytes, wanted the %d bytes of the body`, len(m.Raw()), len(body))
+	}
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+SHBOD=") {
+			t.Fatalf(`Large body was buffered with %q`, cmd)
+		}
+	}
+}
+
+func TestConcurrentRoundTripsAreSerialized(t *testing.T) {
+	m, c := newTestSetup(t)
+
+	const requests = 3
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		path := fmt.Sprintf("/%d", i)
+		m.Reply(fmt.Sprintf(`+SHREQ="%s",1`, path), "OK\n\n+SHREQ: \"GET\",204,0")
+		go func() {
+			req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com"+path, nil)
+			_, err := c.RoundTrip(req)
+			errs <- err
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf(`Unexpected error: %v`, err)
+		}
+	}
+
+	connected := false
+	for _, cmd := range m.Commands() {
+		switch {
+		case cmd == "+SHCONN":
+			if connected {
+				t.Fatalf(`Requests interleaved: %q`, m.Commands())
+			}
+			connected = true
+		case cmd == "+SHDISC":
+			connected = false
+		case strings.HasPrefix(cmd, "+SHCONF") && connected:
+			t.Fatalf(`Requests interleaved: %q`, m.Commands())
+		}
+	}
+}
+
+func TestRoundTripEscapesQuery(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/search?q=hello%20world&city=Espoo%20%C3%A4%22x%22&page=2",1`, "OK\n\n+SHREQ: \"GET\",204,0")
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "http://example.com/search", nil)
+	req.URL.RawQuery = `q=hello world&city=Espoo ä"x"&page=2`
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf(`Got status %d, wanted 204: %q`, resp.StatusCode, m.Commands())
+	}
+}
+
+func TestRoundTripRejectsMismatchedContentLength(t *testing.T) {
+	tests := map[string]struct {
+		body   io.Reader
+		header string
+	}{
+		"longer than body":  {body: strings.NewReader("hello"), header: "10"},
+		"shorter than body": {body: strings.NewReader("hello"), header: "3"},
+		"without body":      {header: "5"},
+		"not a number":      {body: strings.NewReader("hello"), header: "five"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestSetup(t)
+
+			req, _ := nethttp.NewRequest(nethttp.MethodPost, "http://example.com/upload", tc.body)
+			req.Header.Set("Content-Length", tc.header)
+			if _, err := c.RoundTrip(req); err == nil {
+				t.Fatal(`Expected an error for a mismatched Content-Length`)
+			}
+			if got := m.Commands(); len(got) != 0 {
+				t.Fatalf(`Got commands %q, wanted none`, got)
+			}
+		})
+	}
+}
+
+func TestUploadTimeoutScalesWithSize(t *testing.T) {
+	small := uploadTimeout(1024, serialBaud)
+	large := uploadTimeout(10240, serialBaud)
+	if small != minUploadTimeout {
+		t.Fatalf(`Got %v for 1 KiB, wanted the minimum %v`, small, minUploadTimeout)
+	}
+	// 10240 bytes * 10 bits / 115200 baud = 889 ms, doubled
+	if large < 1700*time.Millisecond || large > 1800*time.Millisecond {
+		t.Fatalf(`Got %v for 10 KiB, wanted about 1.78s`, large)
+	}
+	if got := uploadTimeout(10240, 9600); got != maxUploadTimeout {
+		t.Fatalf(`Got %v at 9600 baud, wanted the maximum %v`, got, maxUploadTimeout)
+	}
+}
+
+func TestUploadCertUsesComputedTimeout(t *testing.T) {
+	f, err := ioutil.TempFile("", "cert*.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(bytes.Repeat([]byte("A"), 10240))
+	f.Close()
+
+	m, c := newTestSetup(t)
+	cmd := fmt.Sprintf(`+CFSWFILE=3,"root.pem",0,10240,%d`, uploadTimeout(10240, serialBaud).Milliseconds())
+	m.Reply("+CFSGFRS?", "+CFSGFRS: 100000\nOK")
+	m.Reply(cmd, "DOWNLOAD")
+	m.ReplyRaw("OK")
+
+	if err := c.uploadCert(f.Name(), DefaultCertName); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if !containsCommand(m.Commands(), cmd) {
+		t.Fatalf(`Got commands %q, wanted %q`, m.Commands(), cmd)
+	}
+	if len(m.Raw()) != 10240 {
+		t.Fatalf(`Module received %d bytes, wanted 10240`, len(m.Raw()))
+	}
+}
+
+func containsCommand(cmds []string, cmd string) bool {
+	for _, c := range cmds {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTestCert(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "cert*.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(contents)
+	f.Close()
+	return f.Name()
+}
+
+func TestUploadTwoNamedCerts(t *testing.T) {
+	first := writeTestCert(t, "first")
+	defer os.Remove(first)
+	second := writeTestCert(t, "second")
+	defer os.Remove(second)
+
+	m, c := newTestSetup(t)
+	m.Reply("+CFSGFRS?", "+CFSGFRS: 100000\nOK")
+	m.Reply(`+CFSWFILE=3,"a.pem",0,5,1000`, "DOWNLOAD")
+	m.Reply(`+CFSWFILE=3,"b.pem",0,6,1000`, "DOWNLOAD")
+	m.ReplyRaw("OK")
+
+	if err := c.UploadCert(first, "a.pem"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if err := c.UploadCert(second, "b.pem"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if string(m.Raw()) != "firstsecond" {
+		t.Fatalf(`Module received %q, wanted "firstsecond"`, m.Raw())
+	}
+	for _, cmd := range []string{`+CSSLCFG="convert",2,"a.pem"`, `+CSSLCFG="convert",2,"b.pem"`} {
+		if !containsCommand(m.Commands(), cmd) {
+			t.Fatalf(`Got commands %q, wanted %q`, m.Commands(), cmd)
+		}
+	}
+
+	req, _ := nethttp.NewRequest("GET", "https://example.com/", nil)
+	if name, err := c.certFor(req.WithContext(WithCert(req.Context(), "b.pem"))); err != nil || name != "b.pem" {
+		t.Fatalf(`Got cert %q, %v, wanted "b.pem"`, name, err)
+	}
+	if name, err := c.certFor(req); err != nil || name != "" {
+		t.Fatalf(`Got cert %q, %v, wanted the default ""`, name, err)
+	}
+
+	if err := c.DeleteCert("a.pem"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if !containsCommand(m.Commands(), `+CFSDFILE=3,"a.pem"`) {
+		t.Fatalf(`Got commands %q, wanted +CFSDFILE`, m.Commands())
+	}
+	if _, err := c.certFor(req.WithContext(WithCert(req.Context(), "a.pem"))); err == nil {
+		t.Fatal(`Expected an error selecting a deleted certificate`)
+	}
+	if err := c.DeleteCert("a.pem"); err == nil {
+		t.Fatal(`Expected an error deleting a certificate twice`)
+	}
+}
+
+func TestUploadCertFilesystemFull(t *testing.T) {
+	cert := writeTestCert(t, "certificate")
+	defer os.Remove(cert)
+
+	m, c := newTestSetup(t)
+	m.Reply("+CFSGFRS?", "+CFSGFRS: 4\nOK")
+
+	err := c.UploadCert(cert, "a.pem")
+	if err == nil || !strings.Contains(err.Error(), "full") {
+		t.Fatalf(`Got %v, wanted a filesystem full error`, err)
+	}
+	for _, cmd := range m.Commands() {
+		if strings.HasPrefix(cmd, "+CFSWFILE") {
+			t.Fatalf(`Unexpected %s on a full filesystem`, cmd)
+		}
+	}
+}
+
+func TestCertForHost(t *testing.T) {
+	_, c := newTestSetup(t)
+	c.certName = DefaultCertName
+	c.certs = map[string]bool{DefaultCertName: true, "a.pem": true, "b.pem": true}
+	c.hostCert = map[string]string{
+		"a.example.com":      "a.pem",
+		"b.example.com:8443": "b.pem",
+		"c.example.com":      "missing.pem",
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://a.example.com/", "a.pem"},
+		{"https://a.example.com:443/path", "a.pem"},
+		{"https://b.example.com:8443/", "b.pem"},
+		{"https://b.example.com/", DefaultCertName},
+		{"https://other.example.com/", DefaultCertName},
+	}
+	for _, test := range tests {
+		req, _ := nethttp.NewRequest(nethttp.MethodGet, test.url, nil)
+		if got, err := c.certFor(req); err != nil || got != test.want {
+			t.Errorf(`%s: got cert %q, %v, wanted %q`, test.url, got, err, test.want)
+		}
+	}
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "https://a.example.com/", nil)
+	if got, _ := c.certFor(req.WithContext(WithCert(req.Context(), "b.pem"))); got != "b.pem" {
+		t.Errorf(`Got cert %q, wanted "b.pem" given with WithCert`, got)
+	}
+	req, _ = nethttp.NewRequest(nethttp.MethodGet, "https://c.example.com/", nil)
+	if _, err := c.certFor(req); err == nil {
+		t.Error(`Expected an error for a certificate which isn't uploaded`)
+	}
+}
+
+func TestRoundTripSelectsCertForHost(t *testing.T) {
+	m, c := newTestSetup(t)
+	m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+	c.certs = map[string]bool{"a.pem": true}
+	c.hostCert = map[string]string{"a.example.com": "a.pem"}
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, "https://a.example.com/", nil)
+	if _, err := c.RoundTrip(req); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if !containsCommand(m.Commands(), `+SHSSL=1,"a.pem"`) {
+		t.Fatalf(`Got commands %q, wanted +SHSSL=1,"a.pem"`, m.Commands())
+	}
+}
+
+func TestRoundTripReportsTLSInfo(t *testing.T) {
+	tests := map[string]struct {
+		status           string
+		wantCert         string
+		wantCAConfigured bool
+	}{
+		"CA configured": {
+			status:           `+SHSSL: 1,"a.pem",""`,
+			wantCert:         "a.pem",
+			wantCAConfigured: true,
+		},
+		"no CA": {
+			status:           `+SHSSL: 1,"",""`,
+			wantCert:         "",
+			wantCAConfigured: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestSetup(t)
+			m.Reply(`+SHREQ="/",1`, "OK\n\n+SHREQ: \"GET\",200,0")
+			m.Reply("+SHSSL?", tc.status+"\nOK")
+			c.certs = map[string]bool{"a.pem": true}
+			c.certName = tc.wantCert
+
+			req, _ := nethttp.NewRequest(nethttp.MethodGet, "https://example.com/", nil)
+			resp, err := c.RoundTrip(req)
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if resp.TLS != nil {
+				t.Fatalf(`Got TLS state %+v, wanted none as the module doesn't report it`, resp.TLS)
+			}
+			info := c.TLSInfo()
+			if info.ConfiguredVersion != tls.VersionTLS12 || info.CertName != tc.wantCert || info.CAConfigured != tc.wantCAConfigured {
+				t.Fatalf(`Got %+v, wanted TLS 1.2 with CA %q configured %v`, info, tc.wantCert, tc.wantCAConfigured)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(m *fakemodem.Modem)
+		want    string
+		wantErr bool
+	}{
+		"connected": {
+			setup: func(m *fakemodem.Modem) {},
+			want:  "+SHSTATE?|+SHDISC|+CNACT=0",
+		},
+		"not connected": {
+			setup: func(m *fakemodem.Modem) { m.Reply("+SHSTATE?", "+SHSTATE: 0\nOK") },
+			want:  "+SHSTATE?|+CNACT=0",
+		},
+		"disconnect fails": {
+			setup:   func(m *fakemodem.Modem) { m.Reply("+SHDISC", "ERROR") },
+			want:    "+SHSTATE?|+SHDISC|+CNACT=0",
+			wantErr: true,
+		},
+		"deactivate fails": {
+			setup:   func(m *fakemodem.Modem) { m.Reply("+CNACT=0", "ERROR") },
+			want:    "+SHSTATE?|+SHDISC|+CNACT=0",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestSetup(t)
+			tc.setup(m)
+			c.appNetwork = module.NewAppNetwork(c.modem)
+
+			err := c.Close()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf(`Got error %v, wanted error: %v`, err, tc.wantErr)
+			}
+			if got := strings.Join(m.Commands(), "|"); got != tc.want {
+				t.Fatalf(`Got commands %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+}
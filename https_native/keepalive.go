@@ -0,0 +1,57 @@
+package https
+
+import (
+	"context"
+	"time"
+
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+// startKeepAlive checks the application network every interval and reactivates it if it's inactive
+func (c *Client) startKeepAlive(interval time.Duration) {
+	c.keepAliveStop = make(chan struct{})
+	c.keepAliveDone = make(chan struct{})
+	go func() {
+		defer close(c.keepAliveDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.keepAliveStop:
+				return
+			case <-ticker.C:
+			}
+			c.mutex.Lock()
+			c.keepAppNetworkAlive()
+			c.mutex.Unlock()
+		}
+	}()
+}
+
+// keepAppNetworkAlive reactivates the application network if the module reports it inactive
+func (c *Client) keepAppNetworkAlive() {
+	active, _, err := c.appNetwork.Status()
+	if err != nil {
+		output.Println("Checking application network failed:", err)
+		return
+	}
+	if active {
+		return
+	}
+	output.Println("Application network inactive, reactivating")
+	if err := c.appNetwork.Activate(context.Background()); err != nil {
+		output.Println("Reactivating application network failed:", err)
+		return
+	}
+	output.Println("Application network reactivated")
+}
+
+func (c *Client) stopKeepAlive() {
+	if c.keepAliveStop == nil {
+		return
+	}
+	close(c.keepAliveStop)
+	<-c.keepAliveDone
+	c.keepAliveStop = nil
+}
@@ -0,0 +1,66 @@
+package https
+
+import (
+	"io"
+	"mime/multipart"
+	nethttp "net/http"
+	"sort"
+)
+
+// FilePart is a file to be uploaded as part of a multipart/form-data request
+type FilePart struct {
+	FieldName string
+	FileName  string
+	Content   io.Reader
+}
+
+// NewMultipartRequest returns a POST request to url with a multipart/form-data body
+// containing the given form fields and files, to be executed with a net/http Client using Client as Transport.
+// The body is written as it is read, so file contents are not buffered up front.
+// The request must be sent, or its Body closed, for the goroutine writing the body to stop.
+// File contents which are io.Closers, e.g. *os.File, are closed once it has stopped.
+func NewMultipartRequest(url string, fields map[string]string, files []FilePart) (*nethttp.Request, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	req, err := nethttp.NewRequest(nethttp.MethodPost, url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	go func() {
+		// writing fails with io.ErrClosedPipe once the body is closed, ending the goroutine
+		pw.CloseWithError(writeMultipartBody(mw, fields, files))
+		for _, file := range files {
+			if c, ok := file.Content.(io.Closer); ok {
+				c.Close()
+			}
+		}
+	}()
+
+	return req, nil
+}
+
+func writeMultipartBody(mw *multipart.Writer, fields map[string]string, files []FilePart) error {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := mw.WriteField(key, fields[key]); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		w, err := mw.CreateFormFile(file.FieldName, file.FileName)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, file.Content); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
@@ -0,0 +1,34 @@
+package https
+
+import "sync/atomic"
+
+// readProgress tracks how many bytes of the response body being read the module has yet to deliver.
+// Only bytes actually added to the body are counted, not the lengths the module announces.
+type readProgress struct {
+	remaining int64
+}
+
+func (p *readProgress) start(total int) {
+	atomic.StoreInt64(&p.remaining, int64(total))
+}
+
+// consume records n bytes as delivered and returns how many are still remaining
+func (p *readProgress) consume(n int) int {
+	remaining := atomic.AddInt64(&p.remaining, -int64(n))
+	if remaining < 0 {
+		atomic.StoreInt64(&p.remaining, 0)
+		return 0
+	}
+	return int(remaining)
+}
+
+func (p *readProgress) get() int {
+	return int(atomic.LoadInt64(&p.remaining))
+}
+
+// RemainingBytes returns how many bytes of the response body currently being read by RoundTrip
+// the module has yet to deliver, or 0 if no response body is being read.
+// It is safe to call from another goroutine while RoundTrip is running, e.g. for progress reporting.
+func (c *Client) RemainingBytes() int {
+	return c.progress.get()
+}
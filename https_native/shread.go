@@ -0,0 +1,91 @@
+package https
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxHeaderLine is the longest line inspected for a "+SHREAD: <len>" header
+const maxHeaderLine = 32
+
+// shreadReader sits between the module and the at package, passing on everything read from the module
+// except the response body data following "+SHREAD: <len>" lines while a body is being read.
+// The at package reads the module line by line, which would lose the line breaks of the body
+// and mangle binary data, so the <len> bytes following the header are given to a sink instead.
+type shreadReader struct {
+	r         io.Reader
+	mutex     sync.Mutex
+	line      []byte
+	remaining int
+	sink      func([]byte)
+}
+
+func newSHREADReader(r io.Reader) *shreadReader {
+	return &shreadReader{r: r}
+}
+
+// divert makes the body data following "+SHREAD:" lines be passed to sink in the order it is read,
+// until called again with a nil sink
+func (s *shreadReader) divert(sink func([]byte)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sink = sink
+}
+
+func (s *shreadReader) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		if forwarded := s.filter(p[:n]); forwarded > 0 || err != nil {
+			return forwarded, err
+		}
+	}
+}
+
+// filter removes body data from b, in place, passing it to the sink.
+// It returns the number of bytes left in b.
+func (s *shreadReader) filter(b []byte) int {
+	s.mutex.Lock()
+	var data []byte
+	kept := 0
+	for _, c := range b {
+		if s.remaining > 0 {
+			data = append(data, c)
+			s.remaining--
+			continue
+		}
+		b[kept] = c
+		kept++
+		if c != '\n' {
+			if len(s.line) < maxHeaderLine {
+				s.line = append(s.line, c)
+			}
+			continue
+		}
+		if length, ok := parseSHREADHeader(string(s.line)); ok && s.sink != nil {
+			s.remaining = length
+		}
+		s.line = s.line[:0]
+	}
+	sink := s.sink
+	s.mutex.Unlock()
+
+	if len(data) > 0 && sink != nil {
+		sink(data)
+	}
+	return kept
+}
+
+// parseSHREADHeader returns the length from a "+SHREAD: <len>" line
+func parseSHREADHeader(line string) (int, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "+SHREAD:") {
+		return 0, false
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "+SHREAD:")))
+	if err != nil || length <= 0 {
+		return 0, false
+	}
+	return length, true
+}
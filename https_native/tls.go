@@ -0,0 +1,68 @@
+package https
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+// SSL context used for HTTPS requests, and the TLS version it is configured with
+const (
+	sslContext = 1
+	sslVersion = 3 // TLS 1.2
+)
+
+// sslVersions maps +CSSLCFG "sslversion" values to crypto/tls versions
+var sslVersions = map[int]uint16{
+	1: tls.VersionTLS10,
+	2: tls.VersionTLS11,
+	3: tls.VersionTLS12,
+}
+
+// TLSInfo describes the SSL configuration the latest HTTPS request was made with.
+// The module reports neither the negotiated version nor the result of verifying the server certificate,
+// so ConfiguredVersion is the version the SSL context is configured with, and CAConfigured only tells
+// that the module had CA certificate CertName to verify the server against.
+type TLSInfo struct {
+	ConfiguredVersion uint16
+	CertName          string
+	CAConfigured      bool
+}
+
+// TLSInfo returns the TLSInfo of the latest successful HTTPS request made by the Client.
+// Response.TLS is left nil, as the module doesn't report the state of the connection.
+func (c *Client) TLSInfo() TLSInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.tlsInfo
+}
+
+// querySSLStatus returns the TLSInfo of the current HTTPS connection, read from "+SHSSL: <index>,<calist>,<certname>".
+// certName is the one requested with +SHSSL, used if the module can't be queried.
+func (c *Client) querySSLStatus(certName string) TLSInfo {
+	info := TLSInfo{
+		ConfiguredVersion: sslVersions[sslVersion],
+		CertName:          certName,
+	}
+	r, err := c.modem.Command("+SHSSL?")
+	if err == nil {
+		idx := 0
+		calist := ""
+		clientCert := ""
+		err = parseResponse_SHSSL_READ(r, &idx, &calist, &clientCert)
+		if err == nil && idx == sslContext {
+			info.CertName = strings.Trim(calist, `"`)
+		}
+	}
+	if err != nil {
+		output.Println("Unable to query SSL status:", err)
+	}
+	info.CAConfigured = info.CertName != ""
+	return info
+}
+
+func sslVersionCommand() string {
+	return fmt.Sprintf(`+CSSLCFG="sslversion",%d,%d`, sslContext, sslVersion)
+}
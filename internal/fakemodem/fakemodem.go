@@ -0,0 +1,136 @@
+// Package fakemodem provides an in-memory stand-in for the SIM7000 module
+// for testing code which talks to the module using AT commands.
+package fakemodem
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// Modem implements io.ReadWriter, records the commands written to it
+// and replies to them with canned responses
+type Modem struct {
+	mutex    sync.Mutex
+	replies  map[string][]string
+	data     map[string]string
+	rawReply string
+	commands []string
+	raw      []byte
+
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	output chan string
+}
+
+// New returns a Modem which replies "OK" to every command until told otherwise
+func New() *Modem {
+	pr, pw := io.Pipe()
+	m := &Modem{
+		replies: make(map[string][]string),
+		data:    make(map[string]string),
+		pr:      pr,
+		pw:      pw,
+		output:  make(chan string, 64),
+	}
+	go func() {
+		for s := range m.output {
+			if _, err := m.pw.Write([]byte(s)); err != nil {
+				return
+			}
+		}
+	}()
+	return m
+}
+
+// Reply sets the replies for cmd, which is given without the "AT" prefix.
+// Each issued command consumes one reply, the last one is repeated.
+// Lines in a reply are separated by "\n". An empty reply means the module doesn't respond at all.
+func (m *Modem) Reply(cmd string, replies ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.replies[cmd] = replies
+}
+
+// ReplyData sets data which is sent as it is, without converting line endings,
+// after the reply to cmd, e.g. binary data following a header line
+func (m *Modem) ReplyData(cmd string, data string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.data[cmd] = data
+}
+
+// ReplyRaw sets the reply sent whenever data which isn't an AT command is written, e.g. file contents after DOWNLOAD
+func (m *Modem) ReplyRaw(reply string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rawReply = reply
+}
+
+// Inject sends unsolicited lines from the module
+func (m *Modem) Inject(lines string) {
+	m.send(lines)
+}
+
+// Commands returns the commands issued so far, without the "AT" prefix
+func (m *Modem) Commands() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.commands...)
+}
+
+// Raw returns all data written to the module which wasn't an AT command
+func (m *Modem) Raw() []byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]byte(nil), m.raw...)
+}
+
+// Read returns the replies of the module
+func (m *Modem) Read(b []byte) (int, error) {
+	return m.pr.Read(b)
+}
+
+// Write accepts commands and data sent to the module
+func (m *Modem) Write(b []byte) (int, error) {
+	s := string(b)
+	m.mutex.Lock()
+	var reply, data string
+	switch {
+	case strings.HasPrefix(s, "AT"):
+		cmd := strings.TrimRight(strings.TrimPrefix(s, "AT"), "\r\n")
+		m.commands = append(m.commands, cmd)
+		reply = "OK"
+		data = m.data[cmd]
+		if replies, found := m.replies[cmd]; found && len(replies) > 0 {
+			reply = replies[0]
+			if len(replies) > 1 {
+				m.replies[cmd] = replies[1:]
+			}
+		}
+	case strings.HasPrefix(s, "\x1b"):
+		// escape sequence, not answered
+	default:
+		m.raw = append(m.raw, b...)
+		reply = m.rawReply
+	}
+	m.mutex.Unlock()
+
+	m.send(reply)
+	if data != "" {
+		m.output <- data
+	}
+	return len(b), nil
+}
+
+// Close makes subsequent reads return io.EOF
+func (m *Modem) Close() error {
+	return m.pw.Close()
+}
+
+func (m *Modem) send(lines string) {
+	if lines == "" {
+		return
+	}
+	m.output <- "\r\n" + strings.ReplaceAll(lines, "\n", "\r\n") + "\r\n"
+}
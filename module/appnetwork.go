@@ -0,0 +1,99 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// appNetworkTimeout is how long to wait for "+APP PDP:" after +CNACT=1
+var appNetworkTimeout = 10 * time.Second
+
+// AppNetwork controls the application network (+CNACT) used by the module's
+// internal IP stack, e.g. by the +SH* HTTP(S) commands
+type AppNetwork struct {
+	modem *at.AT
+}
+
+// NewAppNetwork returns an AppNetwork using the given modem
+func NewAppNetwork(modem *at.AT) *AppNetwork {
+	return &AppNetwork{modem: modem}
+}
+
+// Activate activates the application network with +CNACT=1 and waits for the module
+// to report it active with "+APP PDP: ACTIVE". Nothing is done if it is already active.
+func (n *AppNetwork) Activate(ctx context.Context) error {
+	if active, _, err := n.Status(); err == nil && active {
+		return nil
+	}
+
+	result := make(chan string, 1)
+	err := n.modem.AddIndication("+APP PDP:", func(info []string) {
+		select {
+		case result <- strings.TrimSpace(strings.TrimPrefix(info[0], "+APP PDP:")):
+		default:
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("Adding indication for +APP PDP: failed: %w", err)
+	}
+	defer n.modem.CancelIndication("+APP PDP:")
+
+	if _, err := n.modem.Command("+CNACT=1"); err != nil {
+		return fmt.Errorf("+CNACT=1 failed: %w", moduleError(err))
+	}
+
+	timeout := time.NewTimer(appNetworkTimeout)
+	defer timeout.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout.C:
+		return errors.New("+CNACT=1 failed: no +APP PDP: indication received")
+	case state := <-result:
+		if state != "ACTIVE" {
+			return fmt.Errorf("+CNACT=1 failed: application network %s", state)
+		}
+	}
+	return nil
+}
+
+// Deactivate deactivates the application network with +CNACT=0
+func (n *AppNetwork) Deactivate() error {
+	if _, err := n.modem.Command("+CNACT=0"); err != nil {
+		return fmt.Errorf("+CNACT=0 failed: %w", moduleError(err))
+	}
+	return nil
+}
+
+// Status returns whether the application network is active and the IP address it got
+func (n *AppNetwork) Status() (bool, string, error) {
+	resp, err := n.modem.Command("+CNACT?")
+	if err != nil {
+		return false, "", fmt.Errorf("+CNACT? failed: %w", moduleError(err))
+	}
+	return ParseCNACTResp(resp)
+}
+
+// ParseCNACTResp returns whether the application network is active, and its IP address,
+// from a "+CNACT: <status>,<ip>" response
+func ParseCNACTResp(resp []string) (bool, string, error) {
+	line, found := Response(resp).Line("+CNACT:")
+	if !found {
+		return false, "", errors.New("Response to +CNACT? did not contain +CNACT:")
+	}
+	params, _ := Response(resp).Fields("+CNACT:")
+	if len(params) < 2 {
+		return false, "", fmt.Errorf("Malformed response to +CNACT?: \"%s\"", line)
+	}
+	status, err := strconv.Atoi(params[0])
+	if err != nil {
+		return false, "", fmt.Errorf("Malformed response to +CNACT?: \"%s\"", line)
+	}
+	return status == 1, params[1], nil
+}
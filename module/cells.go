@@ -0,0 +1,84 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CellInfo describes the serving cell or a neighbor cell as reported in engineering mode.
+// Signal levels are in dBm (RSRP, RSSI) and dB (RSRQ, SINR).
+// TAC and CellID are given in hexadecimal like the module reports them.
+type CellInfo struct {
+	Serving bool
+	EARFCN  int
+	PCI     int
+	RSRP    int
+	RSSI    int
+	RSRQ    int
+	SINR    int
+	TAC     string
+	CellID  string
+	MCC     string
+	MNC     string
+}
+
+func (s *sim7000e) GetNeighborCells() ([]CellInfo, error) {
+	// engineering mode with neighbor cell info
+	if _, err := s.Command("+CENG=1,1"); err != nil {
+		return nil, fmt.Errorf("Enabling engineering mode failed: %w", err)
+	}
+	resp, err := s.Command("+CENG?")
+	if err != nil {
+		return nil, fmt.Errorf("+CENG? failed: %w", err)
+	}
+	return ParseCENGResp(resp)
+}
+
+// ParseCENGResp parses the cell list from a +CENG? response on an LTE (CAT-M or NB-IoT) network:
+//
+//	+CENG: <mode>,<Ncell>,<cell num>,<RAT>
+//	+CENG: 0,"<earfcn>,<pci>,<rsrp>,<rssi>,<rsrq>,<sinr>,<tac>,<cellid>,<mcc>,<mnc>,<tx power>"
+//	+CENG: 1,"<earfcn>,<pci>,<rsrp>,<rssi>,<rsrq>,<sinr>"
+//	...
+//
+// Cell 0 is the serving cell, the rest are neighbors, and their number varies.
+func ParseCENGResp(resp []string) ([]CellInfo, error) {
+	cells := make([]CellInfo, 0)
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CENG:") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "+CENG:"))
+		quote := strings.Index(line, `"`)
+		if quote < 0 {
+			// header line describing the mode, no cell data
+			continue
+		}
+		cellIndex, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(line[:quote]), ","))
+		if err != nil {
+			return cells, fmt.Errorf("Malformed cell index in \"%s\": %w", resp[i], err)
+		}
+		fields := strings.Split(strings.Trim(line[quote:], `"`), ",")
+		if len(fields) < 6 {
+			return cells, fmt.Errorf("Malformed cell info \"%s\", expecting at least 6 values, but got %d", resp[i], len(fields))
+		}
+
+		cell := CellInfo{Serving: cellIndex == 0}
+		for j, dst := range []*int{&cell.EARFCN, &cell.PCI, &cell.RSRP, &cell.RSSI, &cell.RSRQ, &cell.SINR} {
+			v, err := strconv.Atoi(strings.TrimSpace(fields[j]))
+			if err != nil {
+				return cells, fmt.Errorf("Malformed cell info \"%s\": %w", resp[i], err)
+			}
+			*dst = v
+		}
+		for j, dst := range []*string{&cell.TAC, &cell.CellID, &cell.MCC, &cell.MNC} {
+			if 6+j < len(fields) {
+				*dst = strings.TrimSpace(fields[6+j])
+			}
+		}
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
@@ -0,0 +1,19 @@
+package module
+
+import (
+	"fmt"
+)
+
+// RunChatScriptDryRun returns a description of each command that running the script would send,
+// in order, along with the expected response, timeout and retries, without touching the module
+func RunChatScriptDryRun(script ChatScript) []string {
+	commands := make([]string, 0, len(script.Commands))
+	for _, cmd := range script.Commands {
+		expect := "any response"
+		if cmd.Response != "" {
+			expect = fmt.Sprintf("expect \"%s\"", cmd.Response)
+		}
+		commands = append(commands, fmt.Sprintf("AT%s (%s, timeout %v, %d retries)", cmd.Command, expect, cmd.Timeout, cmd.Retries))
+	}
+	return commands
+}
@@ -0,0 +1,94 @@
+package module
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunChatScriptDryRun(t *testing.T) {
+	script := ChatScript{
+		Aborts: []string{"ERROR"},
+		Commands: []CommandResponse{
+			NormalCommandResponse("+CPIN?", "+CPIN: READY"),
+			{"+CIICR", "", 30 * time.Second, 0},
+			{"+CSQ", "+CSQ: ", 100 * time.Millisecond, 10},
+		},
+	}
+	want := []string{
+		`AT+CPIN? (expect "+CPIN: READY", timeout 100ms, 0 retries)`,
+		`AT+CIICR (any response, timeout 30s, 0 retries)`,
+		`AT+CSQ (expect "+CSQ: ", timeout 100ms, 10 retries)`,
+	}
+
+	got := RunChatScriptDryRun(script)
+	if len(got) != len(want) {
+		t.Fatalf(`Got %d commands, wanted %d`, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf(`Command %d: got %q, wanted %q`, i, got[i], want[i])
+		}
+	}
+}
+
+func TestDefaultChatScriptIsComposable(t *testing.T) {
+	settings := Settings{APN: "internet"}
+	script := DefaultChatScript(settings)
+
+	ciicr := -1
+	for i, cmd := range script.Commands {
+		if cmd.Command == "+CIICR" {
+			ciicr = i
+		}
+	}
+	if ciicr < 0 {
+		t.Fatal(`Default script does not bring up the connection with +CIICR`)
+	}
+	if script.Commands[ciicr-3].Command != `+CSTT="internet"` {
+		t.Fatalf(`Default script does not configure the APN before +CIICR`)
+	}
+
+	// inserting a command must not affect scripts returned later
+	custom := append([]CommandResponse{}, script.Commands[:ciicr]...)
+	custom = append(custom, NormalCommandResponse(`+CBANDCFG="CAT-M",20`, "OK"))
+	script.Commands = append(custom, script.Commands[ciicr:]...)
+
+	again := DefaultChatScript(settings)
+	if again.Commands[ciicr].Command != "+CIICR" {
+		t.Fatal(`Modifying a default script changed subsequent ones`)
+	}
+	if len(RunChatScriptDryRun(script)) != len(again.Commands)+1 {
+		t.Fatal(`Custom script does not contain the inserted command`)
+	}
+}
+
+func TestCommandResponseHelpers(t *testing.T) {
+	tests := map[string]struct {
+		got  CommandResponse
+		want CommandResponse
+	}{
+		"normal": {
+			got:  NormalCommandResponse("+CPIN?", "+CPIN: READY"),
+			want: CommandResponse{Command: "+CPIN?", Response: "+CPIN: READY", Timeout: 100 * time.Millisecond},
+		},
+		"with retries": {
+			got:  CommandWithRetries("+CSQ", "+CSQ: ", time.Second, 5),
+			want: CommandResponse{Command: "+CSQ", Response: "+CSQ: ", Timeout: time.Second, Retries: 5},
+		},
+		"abort only": {
+			got:  AbortOnlyCommand("+CIFSR", 2*time.Second),
+			want: CommandResponse{Command: "+CIFSR", Timeout: 2 * time.Second},
+		},
+		"long": {
+			got:  LongCommand("+CIICR", "OK"),
+			want: CommandResponse{Command: "+CIICR", Response: "OK", Timeout: 30 * time.Second},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf(`Got %+v, wanted %+v`, tc.got, tc.want)
+			}
+		})
+	}
+}
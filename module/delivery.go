@@ -0,0 +1,171 @@
+package module
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// TP-Status values of a status report: below statusTemporaryError the message was delivered,
+// between it and statusPermanentError the service center is still trying,
+// from statusPermanentError up delivery has failed for good
+const (
+	statusTemporaryError = 0x20
+	statusPermanentError = 0x40
+)
+
+// SendSMSPDUWithReport sends message to number like SendSMSPDU, but requests a delivery report
+// for each part. The message references of the parts are returned, to be given to WaitForDelivery.
+// Delivery reports are routed to the host with +CNMI the first time this is called.
+func (s *sim7000e) SendSMSPDUWithReport(number, message string) ([]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.enableDeliveryReports(); err != nil {
+		return nil, err
+	}
+	refs, err := s.sendSMSPDU(number, message, true)
+	if err != nil {
+		return nil, err
+	}
+	s.expectDeliveryReports(refs)
+	return refs, nil
+}
+
+// WaitForDelivery waits for the delivery report of the message with reference ref,
+// as returned by SendSMSPDUWithReport, and returns whether the message was delivered.
+// Reports telling that the service center is still trying are skipped.
+func (s *sim7000e) WaitForDelivery(ref int, timeout time.Duration) (bool, error) {
+	reports, found := s.deliveryReports(ref)
+	if !found {
+		return false, fmt.Errorf("No message %d awaiting a delivery report", ref)
+	}
+	defer s.forgetDeliveryReports(ref)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-deadline.C:
+			return false, fmt.Errorf("No final delivery report for message %d in %v", ref, timeout)
+		case status := <-reports:
+			switch {
+			case status < statusTemporaryError:
+				return true, nil
+			case status >= statusPermanentError:
+				return false, nil
+			}
+		}
+	}
+}
+
+// enableDeliveryReports routes status reports to the host as "+CDS:" indications
+// and starts handling them. It must be called with the mutex held.
+func (s *sim7000e) enableDeliveryReports() error {
+	if s.deliveryReportsEnabled {
+		return nil
+	}
+	// <mode>,<mt>,<bm>,<ds>,<bfr>: <ds> 1 sends status reports directly as +CDS
+	if _, err := s.modem.Command("+CNMI=2,1,0,1,0"); err != nil {
+		return fmt.Errorf("+CNMI=2,1,0,1,0 failed: %w", moduleError(err))
+	}
+	if err := s.modem.AddIndication("+CDS:", s.handleStatusReport, at.WithTrailingLines(1)); err != nil {
+		return fmt.Errorf("Adding indication for +CDS: failed: %w", err)
+	}
+	s.deliveryReportsEnabled = true
+	return nil
+}
+
+func (s *sim7000e) handleStatusReport(info []string) {
+	if len(info) < 2 {
+		return
+	}
+	ref, status, err := ParseStatusReportPDU(info[1])
+	if err != nil {
+		print("Ignoring delivery report:", err)
+		return
+	}
+	// reports of messages not sent with SendSMSPDUWithReport, or already waited for, are dropped
+	reports, found := s.deliveryReports(ref)
+	if !found {
+		return
+	}
+	select {
+	case reports <- status:
+	default:
+	}
+}
+
+// expectDeliveryReports starts buffering the delivery reports of messages refs until they are waited for.
+// References wrap around, so any reports buffered for an earlier message with the same reference are discarded.
+func (s *sim7000e) expectDeliveryReports(refs []int) {
+	s.deliveryMutex.Lock()
+	defer s.deliveryMutex.Unlock()
+	if s.deliveries == nil {
+		s.deliveries = make(map[int]chan int)
+	}
+	for _, ref := range refs {
+		s.deliveries[ref] = make(chan int, 4)
+	}
+}
+
+// deliveryReports returns the channel the delivery reports of message ref are passed through,
+// or false if they aren't expected
+func (s *sim7000e) deliveryReports(ref int) (chan int, bool) {
+	s.deliveryMutex.Lock()
+	defer s.deliveryMutex.Unlock()
+	reports, found := s.deliveries[ref]
+	return reports, found
+}
+
+func (s *sim7000e) forgetDeliveryReports(ref int) {
+	s.deliveryMutex.Lock()
+	defer s.deliveryMutex.Unlock()
+	delete(s.deliveries, ref)
+}
+
+// ParseCMGSResp returns the message reference from a "+CMGS: <mr>" response
+func ParseCMGSResp(resp []string) (int, error) {
+	line, found := Response(resp).Line("+CMGS:")
+	if !found {
+		return 0, errors.New("Response to +CMGS did not contain +CMGS:")
+	}
+	ref, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "+CMGS:")))
+	if err != nil {
+		return 0, fmt.Errorf("Malformed response to +CMGS: \"%s\"", line)
+	}
+	return ref, nil
+}
+
+// ParseStatusReportPDU returns the message reference and TP-Status of an SMS-STATUS-REPORT PDU,
+// given in hex as it follows a "+CDS: <length>" indication
+func ParseStatusReportPDU(pdu string) (int, int, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(pdu))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Malformed status report PDU \"%s\": %w", pdu, err)
+	}
+	malformed := fmt.Errorf("Malformed status report PDU \"%s\"", pdu)
+	if len(b) < 1 {
+		return 0, 0, malformed
+	}
+	i := 1 + int(b[0]) // skip SMSC address
+	if len(b) < i+3 {
+		return 0, 0, malformed
+	}
+	if b[i]&0x03 != 0x02 {
+		return 0, 0, fmt.Errorf("PDU \"%s\" is not a status report", pdu)
+	}
+	ref := int(b[i+1])
+	digits := int(b[i+2])
+	i += 3 + 1 + (digits+1)/2 // recipient address length, type and semi-octets
+	i += 7 + 7                // service center time stamp, discharge time
+	if len(b) < i+1 {
+		return 0, 0, malformed
+	}
+	return ref, int(b[i]), nil
+}
@@ -0,0 +1,162 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/warthog618/modem/at"
+)
+
+// CMEError is a mobile equipment error reported by the module as "+CME ERROR: <err>".
+// Code is -1 if the module reported a message which isn't in the known message table.
+type CMEError struct {
+	Code    int
+	Message string
+}
+
+func (e *CMEError) Error() string {
+	return formatModuleError("CME", e.Code, e.Message, cmeMessages)
+}
+
+// CMSError is a message service error reported by the module as "+CMS ERROR: <err>".
+// Code is -1 if the module reported a message which isn't in the known message table.
+type CMSError struct {
+	Code    int
+	Message string
+}
+
+func (e *CMSError) Error() string {
+	return formatModuleError("CMS", e.Code, e.Message, cmsMessages)
+}
+
+// cmeMessages are the messages of the common +CME ERROR codes, from 3GPP TS 27.007
+var cmeMessages = map[int]string{
+	0:   "phone failure",
+	1:   "no connection to phone",
+	3:   "operation not allowed",
+	4:   "operation not supported",
+	5:   "PH-SIM PIN required",
+	10:  "SIM not inserted",
+	11:  "SIM PIN required",
+	12:  "SIM PUK required",
+	13:  "SIM failure",
+	14:  "SIM busy",
+	15:  "SIM wrong",
+	16:  "incorrect password",
+	17:  "SIM PIN2 required",
+	18:  "SIM PUK2 required",
+	20:  "memory full",
+	21:  "invalid index",
+	22:  "not found",
+	23:  "memory failure",
+	24:  "text string too long",
+	25:  "invalid characters in text string",
+	26:  "dial string too long",
+	27:  "invalid characters in dial string",
+	30:  "no network service",
+	31:  "network timeout",
+	32:  "network not allowed - emergency calls only",
+	100: "unknown",
+	103: "illegal MS",
+	106: "illegal ME",
+	107: "GPRS services not allowed",
+	111: "PLMN not allowed",
+	112: "location area not allowed",
+	113: "roaming not allowed in this location area",
+	132: "service option not supported",
+	133: "requested service option not subscribed",
+	134: "service option temporarily out of order",
+	148: "unspecified GPRS error",
+	149: "PDP authentication failure",
+	150: "invalid mobile class",
+}
+
+// cmsMessages are the messages of the common +CMS ERROR codes, from 3GPP TS 27.005
+var cmsMessages = map[int]string{
+	300: "ME failure",
+	301: "SMS service of ME reserved",
+	302: "operation not allowed",
+	303: "operation not supported",
+	304: "invalid PDU mode parameter",
+	305: "invalid text mode parameter",
+	310: "SIM not inserted",
+	311: "SIM PIN required",
+	312: "PH-SIM PIN required",
+	313: "SIM failure",
+	314: "SIM busy",
+	315: "SIM wrong",
+	316: "SIM PUK required",
+	317: "SIM PIN2 required",
+	318: "SIM PUK2 required",
+	320: "memory failure",
+	321: "invalid memory index",
+	322: "memory full",
+	330: "SMSC address unknown",
+	331: "no network service",
+	332: "network timeout",
+	500: "unknown error",
+}
+
+func formatModuleError(kind string, code int, message string, messages map[int]string) string {
+	if message == "" {
+		message = messages[code]
+	}
+	switch {
+	case code < 0:
+		return fmt.Sprintf("%s error: %s", kind, message)
+	case message == "":
+		return fmt.Sprintf("%s error %d", kind, code)
+	default:
+		return fmt.Sprintf("%s error %d: %s", kind, code, message)
+	}
+}
+
+// moduleError converts the CME and CMS errors of the at package into *CMEError and *CMSError,
+// other errors are returned as is
+func moduleError(err error) error {
+	var cme at.CMEError
+	if errors.As(err, &cme) {
+		code, message := parseErrorValue(string(cme), cmeMessages)
+		return &CMEError{Code: code, Message: message}
+	}
+	var cms at.CMSError
+	if errors.As(err, &cms) {
+		code, message := parseErrorValue(string(cms), cmsMessages)
+		return &CMSError{Code: code, Message: message}
+	}
+	return err
+}
+
+// ParseErrorResp returns the *CMEError or *CMSError contained in resp, or nil if there is none
+func ParseErrorResp(resp []string) error {
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		switch {
+		case strings.HasPrefix(line, "+CME ERROR:"):
+			code, message := parseErrorValue(strings.TrimPrefix(line, "+CME ERROR:"), cmeMessages)
+			return &CMEError{Code: code, Message: message}
+		case strings.HasPrefix(line, "+CMS ERROR:"):
+			code, message := parseErrorValue(strings.TrimPrefix(line, "+CMS ERROR:"), cmsMessages)
+			return &CMSError{Code: code, Message: message}
+		}
+	}
+	return nil
+}
+
+// parseErrorValue splits the <err> of an error result into a code and a message.
+// Numeric values (+CMEE=1) are returned without a message, verbose values (+CMEE=2)
+// are mapped back to their code using messages.
+func parseErrorValue(value string, messages map[int]string) (int, string) {
+	value = strings.TrimSpace(value)
+	if code, err := strconv.Atoi(value); err == nil {
+		return code, ""
+	}
+	for code, message := range messages {
+		if strings.EqualFold(message, value) {
+			return code, value
+		}
+	}
+	return -1, value
+}
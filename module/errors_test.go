@@ -0,0 +1,76 @@
+package module
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  error
+	}{
+		"numeric CME": {
+			input: "+CME ERROR: 10",
+			want:  &CMEError{Code: 10},
+		},
+		"verbose CME": {
+			input: "+CME ERROR: SIM not inserted",
+			want:  &CMEError{Code: 10, Message: "SIM not inserted"},
+		},
+		"unknown verbose CME": {
+			input: "+CME ERROR: something odd",
+			want:  &CMEError{Code: -1, Message: "something odd"},
+		},
+		"numeric CMS": {
+			input: "+CMS ERROR: 500",
+			want:  &CMSError{Code: 500},
+		},
+		"no error": {
+			input: "OK",
+			want:  nil,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ParseErrorResp([]string{"", tc.input})
+			switch want := tc.want.(type) {
+			case nil:
+				if got != nil {
+					t.Fatalf(`Got %v, wanted no error`, got)
+				}
+			case *CMEError:
+				var cme *CMEError
+				if !errors.As(got, &cme) || *cme != *want {
+					t.Fatalf(`Got %#v, wanted %#v`, got, want)
+				}
+			case *CMSError:
+				var cms *CMSError
+				if !errors.As(got, &cms) || *cms != *want {
+					t.Fatalf(`Got %#v, wanted %#v`, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestModuleErrorMessages(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"SIM not inserted":  {err: &CMEError{Code: 10}, want: "CME error 10: SIM not inserted"},
+		"SIM PIN required":  {err: &CMEError{Code: 11}, want: "CME error 11: SIM PIN required"},
+		"PDP auth failure":  {err: &CMEError{Code: 149}, want: "CME error 149: PDP authentication failure"},
+		"unknown CME code":  {err: &CMEError{Code: 999}, want: "CME error 999"},
+		"SMSC unknown":      {err: &CMSError{Code: 330}, want: "CMS error 330: SMSC address unknown"},
+		"text only message": {err: &CMEError{Code: -1, Message: "odd"}, want: "CME error: odd"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.err.Error(); got != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// GNSS controls the GNSS receiver of the module
+type GNSS struct {
+	modem *at.AT
+}
+
+// NewGNSS returns a GNSS using the given modem
+func NewGNSS(modem *at.AT) *GNSS {
+	return &GNSS{modem: modem}
+}
+
+// GNSS returns the GNSS receiver of the module
+func (s *sim7000e) GNSS() *GNSS {
+	return NewGNSS(s.modem)
+}
+
+// PowerOn powers on the GNSS receiver with +CGNSPWR=1
+func (g *GNSS) PowerOn() error {
+	return g.command("+CGNSPWR=1")
+}
+
+// PowerOff powers off the GNSS receiver with +CGNSPWR=0
+func (g *GNSS) PowerOff() error {
+	return g.command("+CGNSPWR=0")
+}
+
+// ColdStart restarts the receiver discarding time, position, almanac and ephemeris (+CGNSCOLD).
+// The first fix takes longest, typically tens of seconds with a clear sky view,
+// but stale data can't slow it down, e.g. after moving far while powered off.
+func (g *GNSS) ColdStart() error {
+	return g.command("+CGNSCOLD")
+}
+
+// WarmStart restarts the receiver keeping time, position and almanac but discarding
+// ephemeris (+CGNSWARM). Faster than a cold start if the module hasn't moved far
+// since the last fix, the ephemeris still has to be received from the satellites.
+func (g *GNSS) WarmStart() error {
+	return g.command("+CGNSWARM")
+}
+
+// HotStart restarts the receiver keeping all data (+CGNSHOT). Gives the fastest fix,
+// but only helps if the last fix was recent (ephemeris is valid for a few hours).
+func (g *GNSS) HotStart() error {
+	return g.command("+CGNSHOT")
+}
+
+// DeleteAssistData stops the receiver using downloaded assistance (XTRA) data with +CGNSXTRA=0,
+// e.g. when it has expired. Follow it with ColdStart to also drop what the receiver has already used.
+func (g *GNSS) DeleteAssistData() error {
+	return g.command("+CGNSXTRA=0")
+}
+
+// XTRAURL is where DownloadAGPSData gets the GNSS assistance data from
+const XTRAURL = "http://iot1.xtracloud.net/xtra3grc.bin"
+
+// xtraPath is where the assistance data is stored on the module filesystem
+const xtraPath = "/customer/Xtra3.bin"
+
+// agpsDownloadTimeout is how long to wait for the assistance data to be downloaded
+var agpsDownloadTimeout = 60 * time.Second
+
+// DownloadAGPSData downloads GNSS assistance (XTRA) data from XTRAURL to the module filesystem
+// with +HTTPTOFS, copies it to the receiver with +CGNSCPY and enables its use with +CGNSXTRA=1.
+// The module must have an active data connection (see AppNetwork) for the download.
+// The data is used from the next ColdStart on, shortening the time to first fix
+// to a few seconds; it is valid for a few days.
+func (g *GNSS) DownloadAGPSData() error {
+	result := make(chan string, 1)
+	err := g.modem.AddIndication("+HTTPTOFS:", func(info []string) {
+		select {
+		case result <- strings.TrimSpace(strings.TrimPrefix(info[0], "+HTTPTOFS:")):
+		default:
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("Adding indication for +HTTPTOFS: failed: %w", err)
+	}
+	defer g.modem.CancelIndication("+HTTPTOFS:")
+
+	if err := g.command(fmt.Sprintf(`+HTTPTOFS="%s","%s"`, XTRAURL, xtraPath)); err != nil {
+		return err
+	}
+	timeout := time.NewTimer(agpsDownloadTimeout)
+	defer timeout.Stop()
+	select {
+	case <-timeout.C:
+		return errors.New("Downloading assistance data timed out")
+	case r := <-result:
+		// <status code>,<data length>
+		if params := splitParams(r); params[0] != "200" {
+			return fmt.Errorf("Downloading assistance data failed with HTTP status %s", params[0])
+		}
+	}
+
+	if err := g.command("+CGNSCPY"); err != nil {
+		return err
+	}
+	return g.command("+CGNSXTRA=1")
+}
+
+// StreamNMEA powers on the GNSS receiver and forwards the NMEA sentences it outputs
+// on the AT port (+CGNSTST=1) to the returned channel until ctx is done,
+// after which the output is stopped and the channel closed.
+// If sentence types such as "GGA" or "RMC" are given, only those are forwarded.
+// Sentences are dropped if the channel isn't read fast enough.
+func (g *GNSS) StreamNMEA(ctx context.Context, types ...string) (<-chan string, error) {
+	if err := g.PowerOn(); err != nil {
+		return nil, err
+	}
+
+	sentences := make(chan string, 16)
+	var mutex sync.Mutex
+	closed := false
+	handler := func(info []string) {
+		sentence := strings.TrimSpace(info[0])
+		if !nmeaTypeWanted(sentence, types) {
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case sentences <- sentence:
+		default:
+		}
+	}
+	if err := g.modem.AddIndication("$", handler); err != nil {
+		return nil, fmt.Errorf("Adding indication for NMEA sentences failed: %w", err)
+	}
+	if err := g.command("+CGNSTST=1"); err != nil {
+		g.modem.CancelIndication("$")
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		g.command("+CGNSTST=0")
+		g.modem.CancelIndication("$")
+		mutex.Lock()
+		closed = true
+		close(sentences)
+		mutex.Unlock()
+	}()
+	return sentences, nil
+}
+
+// nmeaTypeWanted returns true if types is empty or contains the type of sentence,
+// e.g. "GGA" for "$GPGGA,..." and "$GNGGA,..."
+func nmeaTypeWanted(sentence string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	if len(sentence) < 6 {
+		return false
+	}
+	for _, t := range types {
+		if sentence[3:6] == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GNSS) command(cmd string) error {
+	if _, err := g.modem.Command(cmd); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, moduleError(err))
+	}
+	return nil
+}
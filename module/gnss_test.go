@@ -0,0 +1,138 @@
+package module
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LassiHeikkila/SIM7000/internal/fakemodem"
+)
+
+func TestStreamNMEA(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	gnss := newTestSIM7000(m).GNSS()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sentences, err := gnss.StreamNMEA(ctx, "GGA", "RMC")
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	m.Inject("$GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00*74")
+	m.Inject("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47")
+	m.Inject("$GNRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A")
+
+	got := make([]string, 0, 2)
+	timeout := time.After(time.Second)
+	for len(got) < 2 {
+		select {
+		case sentence := <-sentences:
+			got = append(got, sentence[:6])
+		case <-timeout:
+			t.Fatalf(`Got only %q before timing out`, got)
+		}
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != "$GNRMC $GPGGA" {
+		t.Fatalf(`Got sentences %q, wanted GGA and RMC`, got)
+	}
+
+	cancel()
+	for range sentences {
+	}
+	want := []string{"+CGNSPWR=1", "+CGNSTST=1", "+CGNSTST=0"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestGNSSStartModes(t *testing.T) {
+	tests := map[string]struct {
+		start func(*GNSS) error
+		want  string
+	}{
+		"cold":          {start: (*GNSS).ColdStart, want: "+CGNSCOLD"},
+		"warm":          {start: (*GNSS).WarmStart, want: "+CGNSWARM"},
+		"hot":           {start: (*GNSS).HotStart, want: "+CGNSHOT"},
+		"delete assist": {start: (*GNSS).DeleteAssistData, want: "+CGNSXTRA=0"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			if err := tc.start(newTestSIM7000(m).GNSS()); err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got := m.Commands(); len(got) != 1 || got[0] != tc.want {
+				t.Fatalf(`Got commands %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDownloadAGPSData(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	download := `+HTTPTOFS="` + XTRAURL + `","/customer/Xtra3.bin"`
+	m.Reply(download, "OK\n\n+HTTPTOFS: 200,41236")
+
+	if err := newTestSIM7000(m).GNSS().DownloadAGPSData(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{download, "+CGNSCPY", "+CGNSXTRA=1"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestDownloadAGPSDataHTTPError(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply(`+HTTPTOFS="`+XTRAURL+`","/customer/Xtra3.bin"`, "OK\n\n+HTTPTOFS: 404,0")
+
+	if err := newTestSIM7000(m).GNSS().DownloadAGPSData(); err == nil {
+		t.Fatal(`Expected an error when the download fails`)
+	}
+	if got := m.Commands(); len(got) != 1 {
+		t.Fatalf(`Got commands %q, wanted nothing after the failed download`, got)
+	}
+}
+
+func TestGetLocation(t *testing.T) {
+	tests := map[string]struct {
+		cgnsinf string
+		want    Location
+		cell    bool
+	}{
+		"GNSS fix": {
+			cgnsinf: "+CGNSINF: 1,1,20240101120000.000,60.169900,24.938400,20.1,0.00,0.0,1,,1.2,1.5,0.9,,10,6,,,35,,\nOK",
+			want:    Location{Latitude: 60.1699, Longitude: 24.9384, Source: LocationGNSS},
+		},
+		"no GNSS fix": {
+			cgnsinf: "+CGNSINF: 1,0,,,,,,,,,,,,,,,,,,,\nOK",
+			want:    Location{Latitude: 60.17, Longitude: 24.94, Accuracy: 550, Source: LocationCell},
+			cell:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			m.Reply("+CGNSINF", tc.cgnsinf)
+			m.Reply("+CLBS=1,0", "+CLBS: 0,24.940000,60.170000,550\nOK")
+
+			got, err := newTestSIM7000(m).GetLocation()
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %+v, wanted %+v`, got, tc.want)
+			}
+			if cell := len(m.Commands()) > 1; cell != tc.cell {
+				t.Fatalf(`Got commands %q, wanted cell location used: %v`, m.Commands(), tc.cell)
+			}
+		})
+	}
+}
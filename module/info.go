@@ -0,0 +1,78 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ModuleInfo identifies the module and the firmware running on it
+type ModuleInfo struct {
+	Manufacturer string // reported by +CGMI
+	Model        string // reported by +CGMM
+	Revision     string // reported by +CGMR
+}
+
+func (s *sim7000e) GetModuleInfo() (ModuleInfo, error) {
+	var info ModuleInfo
+	var err error
+	if info.Manufacturer, err = s.queryIdentification("+CGMI"); err != nil {
+		return info, err
+	}
+	if info.Model, err = s.queryIdentification("+CGMM"); err != nil {
+		return info, err
+	}
+	if info.Revision, err = s.queryIdentification("+CGMR"); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+func (s *sim7000e) queryIdentification(cmd string) (string, error) {
+	resp, err := s.Command(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	return ParseIdentificationResp(resp, cmd)
+}
+
+// ParseIdentificationResp returns the value from the response to an identification command
+// such as +CGMI, +CGMM or +CGMR.
+// The value may be given bare or prefixed by "<cmd>:" or "Revision:", depending on firmware.
+func ParseIdentificationResp(resp []string, cmd string) (string, error) {
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if line == "" || line == "OK" || line == "AT"+cmd {
+			continue
+		}
+		line = strings.TrimPrefix(line, cmd+":")
+		line = strings.TrimPrefix(line, "Revision:")
+		return strings.TrimSpace(line), nil
+	}
+	return "", errors.New("Response to " + cmd + " did not contain a value")
+}
+
+func (s *sim7000e) GetIMSI() (string, error) {
+	resp, err := s.Command("+CIMI")
+	if err != nil {
+		return "", fmt.Errorf("+CIMI failed: %w", err)
+	}
+	return ParseCIMIResp(resp)
+}
+
+// ParseCIMIResp returns the 15 digit IMSI from a +CIMI response, which is just the bare number
+func ParseCIMIResp(resp []string) (string, error) {
+	imsi, err := ParseIdentificationResp(resp, "+CIMI")
+	if err != nil {
+		return "", err
+	}
+	if len(imsi) != 15 {
+		return "", fmt.Errorf("Invalid IMSI \"%s\", expecting 15 digits", imsi)
+	}
+	for _, r := range imsi {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("Invalid IMSI \"%s\", expecting 15 digits", imsi)
+		}
+	}
+	return imsi, nil
+}
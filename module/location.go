@@ -0,0 +1,111 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LocationSource tells where a Location came from
+type LocationSource int8
+
+// Location sources
+const (
+	LocationGNSS LocationSource = iota // GNSS fix
+	LocationCell                       // cell tower based estimate from +CLBS
+)
+
+func (l LocationSource) String() string {
+	switch l {
+	case LocationGNSS:
+		return "GNSS"
+	case LocationCell:
+		return "cell"
+	default:
+		return "unknown"
+	}
+}
+
+// Location is a position in decimal degrees.
+// Accuracy is the estimated error in meters, 0 if unknown.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64
+	Source    LocationSource
+}
+
+// clbsTimeout is how long the module may take to get a cell based location
+var clbsTimeout = 60 * time.Second
+
+// GetLocation returns the GNSS position if the receiver has a fix, and otherwise a coarse estimate
+// based on the serving cell from the module's location service (+CLBS), which needs an active
+// application network (see AppNetwork). The GNSS receiver isn't powered on by GetLocation.
+func (s *sim7000e) GetLocation() (Location, error) {
+	resp, err := s.Command("+CGNSINF")
+	if err == nil {
+		fix, location, err := ParseCGNSINFResp(resp)
+		if err == nil && fix {
+			return location, nil
+		}
+	}
+
+	resp, err = s.commandWithTimeout("+CLBS=1,0", clbsTimeout)
+	if err != nil {
+		return Location{}, fmt.Errorf("+CLBS=1,0 failed: %w", err)
+	}
+	return ParseCLBSResp(resp)
+}
+
+// ParseCGNSINFResp returns whether the receiver has a fix, and the position if it does, from a
+//
+//	+CGNSINF: <run status>,<fix status>,<UTC date & time>,<latitude>,<longitude>,...
+//
+// response
+func ParseCGNSINFResp(resp []string) (bool, Location, error) {
+	line, found := Response(resp).Line("+CGNSINF:")
+	if !found {
+		return false, Location{}, errors.New("Response to +CGNSINF did not contain +CGNSINF:")
+	}
+	params, _ := Response(resp).Fields("+CGNSINF:")
+	if len(params) < 5 {
+		return false, Location{}, fmt.Errorf("Malformed response to +CGNSINF: \"%s\"", line)
+	}
+	if params[1] != "1" {
+		return false, Location{}, nil
+	}
+	latitude, err := strconv.ParseFloat(params[3], 64)
+	if err != nil {
+		return false, Location{}, fmt.Errorf("Malformed latitude in \"%s\": %w", line, err)
+	}
+	longitude, err := strconv.ParseFloat(params[4], 64)
+	if err != nil {
+		return false, Location{}, fmt.Errorf("Malformed longitude in \"%s\": %w", line, err)
+	}
+	return true, Location{Latitude: latitude, Longitude: longitude, Source: LocationGNSS}, nil
+}
+
+// ParseCLBSResp returns the location from a "+CLBS: <locationcode>,<longitude>,<latitude>,<acc>" response
+func ParseCLBSResp(resp []string) (Location, error) {
+	line, found := Response(resp).Line("+CLBS:")
+	if !found {
+		return Location{}, errors.New("Response to +CLBS did not contain +CLBS:")
+	}
+	params, _ := Response(resp).Fields("+CLBS:")
+	if params[0] != "0" {
+		return Location{}, fmt.Errorf("Cell based location failed with code %s", params[0])
+	}
+	if len(params) < 4 {
+		return Location{}, fmt.Errorf("Malformed response to +CLBS: \"%s\"", line)
+	}
+	values := make([]float64, 3)
+	for i := range values {
+		v, err := strconv.ParseFloat(params[i+1], 64)
+		if err != nil {
+			return Location{}, fmt.Errorf("Malformed response to +CLBS: \"%s\"", line)
+		}
+		values[i] = v
+	}
+	return Location{Longitude: values[0], Latitude: values[1], Accuracy: values[2], Source: LocationCell}, nil
+}
@@ -5,7 +5,14 @@ import (
 	"time"
 )
 
-// Module is an interface representing the SIM7000 module
+// Module is an interface representing the SIM7000 module.
+// Further features are available through the Commander, Info, PDP, Radio, Pinger,
+// SMS, USSD, PowerSaver, ConfigSaver and Locator interfaces, which the Module
+// returned by NewSIM7000 and NewAuto also implements, e.g.
+//
+//	if sms, ok := m.(module.SMS); ok {
+//		err = sms.SendSMSPDU(number, message)
+//	}
 type Module interface {
 	Command(cmd string) ([]string, error)
 	Read(buffer []byte) (int, error)
@@ -16,26 +23,155 @@ type Module interface {
 	Close()
 }
 
+// Commander issues AT commands with a timeout and discards unsolicited output
+type Commander interface {
+	SendATCommand(cmd string, timeout time.Duration, expected string) (bool, error)
+	SendATCommandReturnResponse(cmd string, timeout time.Duration) ([]string, error)
+	Flush() error
+}
+
+// Info queries the identity and condition of the module and its network
+type Info interface {
+	GetModuleInfo() (ModuleInfo, error)
+	GetIMSI() (string, error)
+	GetNeighborCells() ([]CellInfo, error)
+	GetTemperature() (float64, error)
+	GetSignalQuality() (rssi int, ber int, err error)
+}
+
+// PDP configures PDP contexts and the APN
+type PDP interface {
+	SetPDPContext(cid int, pdpType, apn string) error
+	GetPDPContexts() ([]PDPContext, error)
+	GetAPN() (string, error)
+}
+
+// Radio controls the functionality level of the module
+type Radio interface {
+	SetRadio(on bool) error
+	SetCFUN(level int, wait bool) error
+	GetRadioState() (bool, error)
+}
+
+// Pinger pings hosts from the module
+type Pinger interface {
+	Ping(host string, count int) ([]PingResult, error)
+}
+
+// SMS sends short messages
+type SMS interface {
+	GetSMSC() (string, error)
+	SetSMSC(number string) error
+	SendSMSPDU(number, message string) error
+	SendSMSPDUWithReport(number, message string) ([]int, error)
+	WaitForDelivery(ref int, timeout time.Duration) (bool, error)
+}
+
+// USSD sends USSD codes
+type USSD interface {
+	SendUSSD(code string) (string, error)
+}
+
+// PowerSaver puts the module to sleep and wakes it up
+type PowerSaver interface {
+	Sleep() error
+	Wake() error
+}
+
+// ConfigSaver saves the module configuration to NVRAM
+type ConfigSaver interface {
+	SaveConfig() error
+}
+
+// Locator gives access to the GNSS receiver and the location of the module
+type Locator interface {
+	GNSS() *GNSS
+	GetLocation() (Location, error)
+}
+
 // Settings contains needed info for connecting the module to network,
 // i.e. what APN to use, username and password for APN,
+// which authentication protocol to use with them (AuthAuto by default),
 // PIN for SIM card, if any (not supported yet),
-// and which serial port to use for communicating with module
+// and which serial port to use for communicating with module.
+// ChatScript replaces DefaultChatScript(settings) if set.
+// ErrorReporting selects how detailed errors the module reports, verbose by default.
+// Echo keeps command echo enabled (ATE1), by default it is disabled with ATE0
+// so that echoed commands don't end up in responses.
+// FlowControl selects the flow control configured on the module with +IFC.
+// The serial package doesn't support RTS/CTS, so with FlowControlHardware it must be
+// enabled on the serial port by other means, e.g. "stty -F <port> crtscts".
+// CommandDelay is how long to wait before each chat script command (DefaultCommandDelay if 0,
+// no delay if negative).
+// If WatchdogInterval is set, the SIM and network state is checked periodically
+// and OnWatchdogChange is called whenever it changes, until the Module is closed.
 type Settings struct {
 	APN                   string
 	Username              string
 	Password              string
+	AuthType              AuthType
 	PIN                   string
 	SerialPort            string
 	MaxConnectionAttempts int
 	TraceLogger           *log.Logger
 	ChatScript            *ChatScript
+	ErrorReporting        ErrorReporting
+	Echo                  bool
+	FlowControl           FlowControl
+	CommandDelay          time.Duration
+	WatchdogInterval      time.Duration
+	OnWatchdogChange      func(previous, current WatchdogState)
 }
 
+// ErrorReporting selects the format of errors reported by the module, configured with +CMEE
+type ErrorReporting int8
+
+// Error reporting formats
+const (
+	ErrorReportingVerbose  ErrorReporting = iota // "+CME ERROR: <message>"
+	ErrorReportingNumeric                        // "+CME ERROR: <code>"
+	ErrorReportingDisabled                       // bare "ERROR"
+)
+
+// FlowControl selects the flow control used between the host and the module
+type FlowControl int8
+
+// Flow control modes
+const (
+	FlowControlNone     FlowControl = iota
+	FlowControlHardware             // RTS/CTS
+)
+
+// AuthType selects the protocol used to authenticate with the APN
+type AuthType int8
+
+// Authentication protocols configurable with +CGAUTH
+const (
+	AuthAuto AuthType = iota // PAP or CHAP, whichever the network asks for
+	AuthNone
+	AuthPAP
+	AuthCHAP
+)
+
+// ChatScript is a sequence of commands run against the module.
+// A response containing any of the Aborts terms fails the script.
+// A response containing any of the RetryableAborts terms is retried after RetryDelay
+// (DefaultRetryDelay if 0) as long as the command has retries left, and fails the script otherwise.
+// OnResponse, if set, is called with each command and the response the module gave to it.
 type ChatScript struct {
-	Aborts   []string
-	Commands []CommandResponse
+	Aborts          []string
+	RetryableAborts []string
+	RetryDelay      time.Duration
+	Commands        []CommandResponse
+	OnResponse      func(cmd string, resp []string)
 }
 
+// DefaultCommandDelay is how long to wait before each chat script command by default
+const DefaultCommandDelay = time.Second
+
+// DefaultRetryDelay is how long to wait before retrying a command which hit a retryable abort term
+const DefaultRetryDelay = 2 * time.Second
+
 type CommandResponse struct {
 	Command  string
 	Response string
@@ -46,3 +182,18 @@ type CommandResponse struct {
 func NormalCommandResponse(cmd string, resp string) CommandResponse {
 	return CommandResponse{cmd, resp, 100 * time.Millisecond, 0}
 }
+
+// CommandWithRetries returns a CommandResponse with the given timeout and retries
+func CommandWithRetries(cmd string, resp string, timeout time.Duration, retries int) CommandResponse {
+	return CommandResponse{cmd, resp, timeout, retries}
+}
+
+// AbortOnlyCommand returns a CommandResponse which accepts any response not containing an abort term
+func AbortOnlyCommand(cmd string, timeout time.Duration) CommandResponse {
+	return CommandResponse{cmd, "", timeout, 0}
+}
+
+// LongCommand returns a CommandResponse for operations which may take up to 30 seconds, such as +CIICR
+func LongCommand(cmd string, resp string) CommandResponse {
+	return CommandResponse{cmd, resp, 30 * time.Second, 0}
+}
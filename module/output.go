@@ -2,7 +2,6 @@ package module
 
 import (
 	"strings"
-	"time"
 
 	"github.com/LassiHeikkila/SIM7000/output"
 )
@@ -21,7 +20,3 @@ func dumpBytes(b []byte) string {
 
 	return builder.String()
 }
-
-func countdown(n int, interval time.Duration) {
-	output.Countdown(n, interval)
-}
@@ -3,6 +3,7 @@ package module
 import (
 	"testing"
 	"strings"
+	"time"
 )
 
 func inputAsLines(input string) []string {
@@ -238,3 +239,450 @@ C: 7,,"","","","INITIAL"`,
 		})
 	}
 }
+
+func TestCIPStatusStringRoundTrip(t *testing.T) {
+	for s := IPStatusUnknown; s <= IPPDPDeact; s++ {
+		t.Run(s.String(), func(t *testing.T) {
+			got := ParseCIPSTATUSResp([]string{"OK", "", "STATE: " + s.String()})
+			if got != s {
+				t.Fatalf(`Got %v, wanted %v`, got, s)
+			}
+		})
+	}
+}
+
+func TestCIPSTATUSConnectionsParsingCIPMUX1(t *testing.T) {
+	input := `OK
+STATE: IP STATUS
+C:
+0,0,"TCP","116.236.221.75","5555","CLOSED"
+C:
+1,1,"TCP","116.236.221.75","5555","CONNECT
+ED"
+C: 2,,"","","","INITIAL"
+C: 3,,"","","","INITIAL"
+C: 4,,"","","","INITIAL"
+C: 5,,"","","","INITIAL"
+C: 6,,"","","","INITIAL"
+C: 7,,"","","","INITIAL"`
+
+	want := []ConnInfo{
+		{Index: 0, Bearer: 0, Protocol: "TCP", RemoteIP: "116.236.221.75", Port: 5555, State: "CLOSED"},
+		{Index: 1, Bearer: 1, Protocol: "TCP", RemoteIP: "116.236.221.75", Port: 5555, State: "CONNECTED"},
+		{Index: 2, State: "INITIAL"},
+		{Index: 3, State: "INITIAL"},
+		{Index: 4, State: "INITIAL"},
+		{Index: 5, State: "INITIAL"},
+		{Index: 6, State: "INITIAL"},
+		{Index: 7, State: "INITIAL"},
+	}
+
+	got, err := ParseCIPSTATUSConnections(inputAsLines(input))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf(`Got %d connections, wanted %d`, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf(`Connection %d: got %+v, wanted %+v`, i, got[i], want[i])
+		}
+	}
+}
+
+func TestCIPSTATUSConnectionsParsingMalformed(t *testing.T) {
+	_, err := ParseCIPSTATUSConnections(inputAsLines(`OK
+STATE: IP STATUS
+C: 0,0,"TCP","116.236.221.75"`))
+	if err == nil {
+		t.Fatal(`Expected an error for truncated connection status`)
+	}
+}
+
+func TestJoinWrappedLines(t *testing.T) {
+	input := `OK
+STATE: IP GPRS
+ACT
+C:
+0,0,"TCP","116.236.221.75","5555","CLOSED"
+C:
+1,1,"TCP","116.236.221.75","5555","CONNECT
+ED"
+C: 2,,"","","","INITIAL"
+
++PDP: DEACT`
+
+	want := []string{
+		`OK`,
+		`STATE: IP GPRSACT`,
+		`C:0,0,"TCP","116.236.221.75","5555","CLOSED"`,
+		`C:1,1,"TCP","116.236.221.75","5555","CONNECTED"`,
+		`C: 2,,"","","","INITIAL"`,
+		``,
+		`+PDP: DEACT`,
+	}
+
+	got := joinWrappedLines(inputAsLines(input), cipstatusPrefixes)
+	if len(got) != len(want) {
+		t.Fatalf(`Got %d lines %q, wanted %d`, len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf(`Line %d: got %q, wanted %q`, i, got[i], want[i])
+		}
+	}
+}
+
+func TestCIPSTATUSResponseParsingWrappedState(t *testing.T) {
+	got := ParseCIPSTATUSResp(inputAsLines(`OK
+STATE: IP GPRS
+ACT
+C:
+0,0,"TCP","116.236.221.75","5555","CLOSED"`))
+	if got != IPGPRSAct {
+		t.Fatalf(`Got %v, wanted %v`, got, IPGPRSAct)
+	}
+}
+
+func TestIdentificationResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		cmd   string
+		input string
+		want  string
+	}{
+		"CGMI": {
+			cmd: "+CGMI",
+			input: `SIMCOM INC.
+
+OK`,
+			want: "SIMCOM INC.",
+		},
+		"CGMM": {
+			cmd: "+CGMM",
+			input: `SIMCOM_SIM7000E
+
+OK`,
+			want: "SIMCOM_SIM7000E",
+		},
+		"CGMR": {
+			cmd: "+CGMR",
+			input: `Revision:1351B03SIM7000E
+
+OK`,
+			want: "1351B03SIM7000E",
+		},
+		"CGMR with prefix": {
+			cmd: "+CGMR",
+			input: `+CGMR: 1351B04SIM7000G
+
+OK`,
+			want: "1351B04SIM7000G",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseIdentificationResp(inputAsLines(tc.input), tc.cmd)
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := ParseIdentificationResp(inputAsLines("\nOK"), "+CGMM"); err == nil {
+		t.Fatal(`Expected an error for a response without a value`)
+	}
+}
+
+func TestCENGResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  []CellInfo
+	}{
+		"serving and neighbors": {
+			input: `+CENG: 1,1,3,CAT-M
++CENG: 0,"6300,181,-98,-67,-11,8,0C03,1A2D01,244,91,23"
++CENG: 1,"6300,412,-104,-73,-15,2"
++CENG: 2,"1650,77,-110,-80,-17,-3"
+
+OK`,
+			want: []CellInfo{
+				{Serving: true, EARFCN: 6300, PCI: 181, RSRP: -98, RSSI: -67, RSRQ: -11, SINR: 8, TAC: "0C03", CellID: "1A2D01", MCC: "244", MNC: "91"},
+				{EARFCN: 6300, PCI: 412, RSRP: -104, RSSI: -73, RSRQ: -15, SINR: 2},
+				{EARFCN: 1650, PCI: 77, RSRP: -110, RSSI: -80, RSRQ: -17, SINR: -3},
+			},
+		},
+		"serving only": {
+			input: `+CENG: 1,1,1,NB-IOT
++CENG: 0,"6352,33,-120,-90,-12,-1,0C03,1A2D02,244,91,23"
+
+OK`,
+			want: []CellInfo{
+				{Serving: true, EARFCN: 6352, PCI: 33, RSRP: -120, RSSI: -90, RSRQ: -12, SINR: -1, TAC: "0C03", CellID: "1A2D02", MCC: "244", MNC: "91"},
+			},
+		},
+		"no cells": {
+			input: `+CENG: 1,1,0,NO SERVICE
+
+OK`,
+			want: []CellInfo{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCENGResp(inputAsLines(tc.input))
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf(`Got %d cells, wanted %d`, len(got), len(tc.want))
+			}
+			for i := range tc.want {
+				if got[i] != tc.want[i] {
+					t.Errorf(`Cell %d: got %+v, wanted %+v`, i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCGDCONTResponseParsing(t *testing.T) {
+	input := `+CGDCONT: 1,"IP","internet","0.0.0.0",0,0,0,0
++CGDCONT: 2,"IPV4V6","ims","0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0",0,0,0,0
++CGDCONT: 3,"Non-IP","nbiot.example",,0,0,0,0
+
+OK`
+	want := []PDPContext{
+		{CID: 1, Type: PDPTypeIP, APN: "internet", Address: "0.0.0.0"},
+		{CID: 2, Type: PDPTypeIPv4v6, APN: "ims", Address: "0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0"},
+		{CID: 3, Type: PDPTypeNonIP, APN: "nbiot.example"},
+	}
+
+	got, err := ParseCGDCONTResp(inputAsLines(input))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf(`Got %d contexts, wanted %d`, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf(`Context %d: got %+v, wanted %+v`, i, got[i], want[i])
+		}
+	}
+}
+
+func TestConstructCGDCONT(t *testing.T) {
+	got, err := constructCGDCONT(1, PDPTypeNonIP, "nbiot.example")
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if want := `+CGDCONT=1,"Non-IP","nbiot.example"`; got != want {
+		t.Fatalf(`Got %s, wanted %s`, got, want)
+	}
+	if _, err := constructCGDCONT(1, "PPP", "internet"); err == nil {
+		t.Fatal(`Expected an error for unsupported PDP type`)
+	}
+}
+
+func TestCMTEResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		enabled     bool
+		temperature float64
+	}{
+		"enabled": {
+			input: `+CMTE: 1,36.50
+
+OK`,
+			enabled:     true,
+			temperature: 36.5,
+		},
+		"below zero": {
+			input: `+CMTE: 1,-12.25
+
+OK`,
+			enabled:     true,
+			temperature: -12.25,
+		},
+		"disabled": {
+			input: `+CMTE: 0,0
+
+OK`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			enabled, temperature, err := ParseCMTEResp(inputAsLines(tc.input))
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if enabled != tc.enabled || temperature != tc.temperature {
+				t.Fatalf(`Got %v, %v, wanted %v, %v`, enabled, temperature, tc.enabled, tc.temperature)
+			}
+		})
+	}
+
+	if _, _, err := ParseCMTEResp(inputAsLines("\nOK")); err == nil {
+		t.Fatal(`Expected an error for a response without +CMTE:`)
+	}
+}
+
+func TestCSQResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		rssi  int
+		ber   int
+	}{
+		"signal": {
+			input: `+CSQ: 20,0
+
+OK`,
+			rssi: 20,
+			ber:  0,
+		},
+		"no signal": {
+			input: `+CSQ: 99,99
+
+OK`,
+			rssi: RSSIUnknown,
+			ber:  99,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rssi, ber, err := ParseCSQResp(inputAsLines(tc.input))
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if rssi != tc.rssi || ber != tc.ber {
+				t.Fatalf(`Got %d,%d, wanted %d,%d`, rssi, ber, tc.rssi, tc.ber)
+			}
+		})
+	}
+}
+
+func TestCIPPINGResponseParsing(t *testing.T) {
+	input := `+CIPPING: 1,"93.184.216.34",3,52
++CIPPING: 2,"93.184.216.34",600000,255
++CIPPING: 3,"93.184.216.34",12,52
+
+OK`
+	want := []PingResult{
+		{Seq: 1, IP: "93.184.216.34", RTT: 300 * time.Millisecond, TTL: 52},
+		{Seq: 2, IP: "93.184.216.34", TTL: 255, Timeout: true},
+		{Seq: 3, IP: "93.184.216.34", RTT: 1200 * time.Millisecond, TTL: 52},
+	}
+
+	got, err := ParseCIPPINGResp(inputAsLines(input))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf(`Got %d results, wanted %d`, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf(`Result %d: got %+v, wanted %+v`, i, got[i], want[i])
+		}
+	}
+}
+
+func TestCIMIResponseParsing(t *testing.T) {
+	got, err := ParseCIMIResp(inputAsLines(`244910123456789
+
+OK`))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got != "244910123456789" {
+		t.Fatalf(`Got %s, wanted 244910123456789`, got)
+	}
+
+	for name, input := range map[string]string{
+		"too short":   "24491012345\n\nOK",
+		"not numeric": "24491012345678X\n\nOK",
+		"missing":     "\nOK",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseCIMIResp(inputAsLines(input)); err == nil {
+				t.Fatal(`Expected an error`)
+			}
+		})
+	}
+}
+
+func TestCNACTResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		active bool
+		ip     string
+	}{
+		"active": {
+			input: `+CNACT: 1,"10.170.42.7"
+
+OK`,
+			active: true,
+			ip:     "10.170.42.7",
+		},
+		"inactive": {
+			input: `+CNACT: 0,"0.0.0.0"
+
+OK`,
+			active: false,
+			ip:     "0.0.0.0",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			active, ip, err := ParseCNACTResp(inputAsLines(tc.input))
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if active != tc.active || ip != tc.ip {
+				t.Fatalf(`Got %v,%s, wanted %v,%s`, active, ip, tc.active, tc.ip)
+			}
+		})
+	}
+
+	if _, _, err := ParseCNACTResp(inputAsLines("\nOK")); err == nil {
+		t.Fatal(`Expected an error when +CNACT: is missing`)
+	}
+}
+
+func TestCSTTResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"with credentials": {
+			input: `+CSTT: "internet","user","secret"
+
+OK`,
+			want: "internet",
+		},
+		"not set": {
+			input: `+CSTT: "","",""
+
+OK`,
+			want: "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCSTTResp(inputAsLines(tc.input))
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+}
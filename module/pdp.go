@@ -0,0 +1,117 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PDPContext is a PDP context definition as reported by +CGDCONT?
+type PDPContext struct {
+	CID     int
+	Type    string
+	APN     string
+	Address string
+}
+
+// PDP types accepted by SetPDPContext
+const (
+	PDPTypeIP     = "IP"
+	PDPTypeIPv6   = "IPV6"
+	PDPTypeIPv4v6 = "IPV4V6"
+	PDPTypeNonIP  = "Non-IP"
+)
+
+func (s *sim7000e) SetPDPContext(cid int, pdpType, apn string) error {
+	cmd, err := constructCGDCONT(cid, pdpType, apn)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Command(cmd); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	return nil
+}
+
+func (s *sim7000e) GetPDPContexts() ([]PDPContext, error) {
+	resp, err := s.Command("+CGDCONT?")
+	if err != nil {
+		return nil, fmt.Errorf("+CGDCONT? failed: %w", err)
+	}
+	return ParseCGDCONTResp(resp)
+}
+
+// GetAPN returns the APN the module uses, as reported by +CSTT?,
+// or by +CGDCONT? for context 1 if none is set with +CSTT
+func (s *sim7000e) GetAPN() (string, error) {
+	resp, err := s.Command("+CSTT?")
+	if err != nil {
+		return "", fmt.Errorf("+CSTT? failed: %w", err)
+	}
+	apn, err := ParseCSTTResp(resp)
+	if err != nil {
+		return "", err
+	}
+	if apn != "" {
+		return apn, nil
+	}
+	contexts, err := s.GetPDPContexts()
+	if err != nil {
+		return "", err
+	}
+	for _, context := range contexts {
+		if context.CID == 1 {
+			return context.APN, nil
+		}
+	}
+	return "", errors.New("No APN configured with +CSTT or for PDP context 1")
+}
+
+// ParseCSTTResp returns the APN from a "+CSTT: <apn>,<user name>,<password>" response
+func ParseCSTTResp(resp []string) (string, error) {
+	params, found := Response(resp).Fields("+CSTT:")
+	if !found {
+		return "", errors.New("Response to +CSTT? did not contain +CSTT:")
+	}
+	return params[0], nil
+}
+
+func constructCGDCONT(cid int, pdpType, apn string) (string, error) {
+	switch pdpType {
+	case PDPTypeIP, PDPTypeIPv6, PDPTypeIPv4v6, PDPTypeNonIP:
+	default:
+		return "", fmt.Errorf("Unsupported PDP type \"%s\"", pdpType)
+	}
+	if cid < 1 {
+		return "", fmt.Errorf("Invalid PDP context identifier %d", cid)
+	}
+	return fmt.Sprintf(`+CGDCONT=%d,"%s","%s"`, cid, pdpType, apn), nil
+}
+
+// ParseCGDCONTResp parses all context definitions from a +CGDCONT? response, given as
+//
+//	+CGDCONT: <cid>,<PDP_type>,<APN>,<PDP_addr>,<d_comp>,<h_comp>...
+func ParseCGDCONTResp(resp []string) ([]PDPContext, error) {
+	contexts := make([]PDPContext, 0)
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CGDCONT:") {
+			continue
+		}
+		params := splitParams(strings.TrimPrefix(line, "+CGDCONT:"))
+		if len(params) < 3 {
+			return contexts, fmt.Errorf("Malformed response to +CGDCONT?: \"%s\"", resp[i])
+		}
+		cid, err := strconv.Atoi(params[0])
+		if err != nil {
+			return contexts, fmt.Errorf("Malformed context identifier in \"%s\": %w", resp[i], err)
+		}
+		context := PDPContext{CID: cid, Type: params[1], APN: params[2]}
+		if len(params) > 3 {
+			context.Address = params[3]
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, nil
+}
@@ -0,0 +1,237 @@
+package module
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// gsm7Alphabet is the GSM 03.38 default alphabet, indexed by septet value
+var gsm7Alphabet = []rune("@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà")
+
+// gsm7Extension maps the characters of the GSM 03.38 extension table to the septet following the escape
+var gsm7Extension = map[rune]byte{
+	'\f': 0x0a,
+	'^':  0x14,
+	'{':  0x28,
+	'}':  0x29,
+	'\\': 0x2f,
+	'[':  0x3c,
+	'~':  0x3d,
+	']':  0x3e,
+	'|':  0x40,
+	'€':  0x65,
+}
+
+const gsm7Escape = 0x1b
+
+// Data coding schemes used for outgoing messages
+const (
+	dcsGSM7 = 0x00
+	dcsUCS2 = 0x08
+)
+
+// Maximum user data of a single message, and of each part of a concatenated one
+const (
+	maxSeptets         = 160
+	maxConcatSeptets   = 153
+	maxUCS2Units       = 70
+	maxConcatUCS2Units = 67
+	concatUDHLength    = 6
+	concatUDHFillBits  = 1 // pads the 6 octet header to a septet boundary
+	concatUDHSeptets   = 7
+)
+
+// First octet of an SMS-SUBMIT, and its flags for requesting a status report
+// and telling that the user data starts with a header
+const (
+	smsSubmit     = 0x01
+	smsSubmitSRR  = 0x20
+	smsSubmitUDHI = 0x40
+)
+
+// smsPDU is an SMS-SUBMIT PDU as sent with +CMGS in PDU mode
+type smsPDU struct {
+	// Hex is the PDU in hex, starting with the (empty) SMSC address
+	Hex string
+	// Length is the length of the TPDU in octets, i.e. without the SMSC address, as given to +CMGS
+	Length int
+}
+
+// encodeSMSSubmit encodes message to number as one or more SMS-SUBMIT PDUs.
+// Messages which fit the GSM 7 bit default alphabet are sent with it, others as UCS2.
+// Messages which don't fit a single PDU are split into a concatenated message
+// identified by ref. If statusReport is set, a delivery report is requested for each PDU.
+func encodeSMSSubmit(number, message string, ref byte, statusReport bool) ([]smsPDU, error) {
+	address, err := encodeAddress(number)
+	if err != nil {
+		return nil, err
+	}
+	firstOctet := byte(smsSubmit)
+	if statusReport {
+		firstOctet |= smsSubmitSRR
+	}
+	if septets, ok := encodeGSM7(message); ok {
+		parts := splitSeptets(septets)
+		pdus := make([]smsPDU, 0, len(parts))
+		for i, part := range parts {
+			var ud []byte
+			var udl int
+			fo := firstOctet
+			if len(parts) == 1 {
+				ud = packSeptets(part, 0)
+				udl = len(part)
+			} else {
+				fo |= smsSubmitUDHI
+				ud = append(concatUDH(ref, len(parts), i+1), packSeptets(part, concatUDHFillBits)...)
+				udl = concatUDHSeptets + len(part)
+			}
+			pdus = append(pdus, buildSubmitPDU(fo, address, dcsGSM7, udl, ud))
+		}
+		return pdus, nil
+	}
+
+	parts := splitUCS2(utf16.Encode([]rune(message)))
+	pdus := make([]smsPDU, 0, len(parts))
+	for i, part := range parts {
+		ud := make([]byte, 0, 2*len(part)+concatUDHLength)
+		fo := firstOctet
+		if len(parts) > 1 {
+			fo |= smsSubmitUDHI
+			ud = append(ud, concatUDH(ref, len(parts), i+1)...)
+		}
+		for _, unit := range part {
+			ud = append(ud, byte(unit>>8), byte(unit))
+		}
+		pdus = append(pdus, buildSubmitPDU(fo, address, dcsUCS2, len(ud), ud))
+	}
+	return pdus, nil
+}
+
+func buildSubmitPDU(firstOctet byte, address []byte, dcs byte, udl int, ud []byte) smsPDU {
+	tpdu := []byte{firstOctet, 0x00} // message reference set by the module
+	tpdu = append(tpdu, address...)
+	tpdu = append(tpdu, 0x00, dcs, byte(udl)) // protocol identifier, data coding scheme, user data length
+	tpdu = append(tpdu, ud...)
+	// "00": use the SMSC configured with +CSCA
+	return smsPDU{
+		Hex:    "00" + strings.ToUpper(hex.EncodeToString(tpdu)),
+		Length: len(tpdu),
+	}
+}
+
+// encodeAddress encodes a phone number as a TP-DA: number of digits, type of address and semi-octets
+func encodeAddress(number string) ([]byte, error) {
+	digits := strings.TrimPrefix(number, "+")
+	if digits == "" {
+		return nil, errors.New("Empty phone number")
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return nil, fmt.Errorf("Invalid phone number \"%s\"", number)
+		}
+	}
+	toa := byte(0x81)
+	if strings.HasPrefix(number, "+") {
+		toa = 0x91
+	}
+	address := []byte{byte(len(digits)), toa}
+	for i := 0; i < len(digits); i += 2 {
+		low := digits[i] - '0'
+		high := byte(0x0f)
+		if i+1 < len(digits) {
+			high = digits[i+1] - '0'
+		}
+		address = append(address, high<<4|low)
+	}
+	return address, nil
+}
+
+// encodeGSM7 returns message as GSM 7 bit septets, extension characters taking two,
+// or false if message contains characters which can't be represented
+func encodeGSM7(message string) ([]byte, bool) {
+	septets := make([]byte, 0, len(message))
+	for _, r := range message {
+		if ext, found := gsm7Extension[r]; found {
+			septets = append(septets, gsm7Escape, ext)
+			continue
+		}
+		index := -1
+		for i, c := range gsm7Alphabet {
+			if c == r && i != gsm7Escape {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, false
+		}
+		septets = append(septets, byte(index))
+	}
+	return septets, true
+}
+
+// splitSeptets splits septets into the parts of a concatenated message, if they don't fit a single one,
+// without separating an escape from the septet it applies to
+func splitSeptets(septets []byte) [][]byte {
+	if len(septets) <= maxSeptets {
+		return [][]byte{septets}
+	}
+	parts := make([][]byte, 0)
+	for len(septets) > 0 {
+		n := maxConcatSeptets
+		if n >= len(septets) {
+			n = len(septets)
+		} else if septets[n-1] == gsm7Escape {
+			n--
+		}
+		parts = append(parts, septets[:n])
+		septets = septets[n:]
+	}
+	return parts
+}
+
+// splitUCS2 splits UTF-16 units into the parts of a concatenated message, if they don't fit a single one,
+// without separating surrogate pairs
+func splitUCS2(units []uint16) [][]uint16 {
+	if len(units) <= maxUCS2Units {
+		return [][]uint16{units}
+	}
+	parts := make([][]uint16, 0)
+	for len(units) > 0 {
+		n := maxConcatUCS2Units
+		if n >= len(units) {
+			n = len(units)
+		} else if utf16.IsSurrogate(rune(units[n-1])) && units[n-1] < 0xdc00 {
+			n--
+		}
+		parts = append(parts, units[:n])
+		units = units[n:]
+	}
+	return parts
+}
+
+// packSeptets packs septets into octets, least significant bit first,
+// starting after fillBits bits of padding
+func packSeptets(septets []byte, fillBits int) []byte {
+	totalBits := fillBits + 7*len(septets)
+	packed := make([]byte, (totalBits+7)/8)
+	bit := fillBits
+	for _, septet := range septets {
+		for i := 0; i < 7; i++ {
+			if septet&(1<<uint(i)) != 0 {
+				packed[bit/8] |= 1 << uint(bit%8)
+			}
+			bit++
+		}
+	}
+	return packed
+}
+
+// concatUDH returns the user data header of part seq of a concatenated message of total parts
+func concatUDH(ref byte, total, seq int) []byte {
+	return []byte{concatUDHLength - 1, 0x00, 0x03, ref, byte(total), byte(seq)}
+}
@@ -0,0 +1,65 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PingResult is the outcome of a single echo request sent with +CIPPING
+type PingResult struct {
+	Seq     int
+	IP      string
+	RTT     time.Duration
+	TTL     int
+	Timeout bool
+}
+
+// pingTimeout is the time the module waits for each echo reply by default
+const pingTimeout = 10 * time.Second
+
+// Ping sends count ICMP echo requests to host and returns the result of each
+func (s *sim7000e) Ping(host string, count int) ([]PingResult, error) {
+	if count < 1 {
+		count = 1
+	}
+	cmd := fmt.Sprintf(`+CIPPING="%s",%d`, host, count)
+	resp, err := s.commandWithTimeout(cmd, time.Duration(count)*pingTimeout+5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	return ParseCIPPINGResp(resp)
+}
+
+// ParseCIPPINGResp parses the "+CIPPING: <replyId>,<IP address>,<replyTime>,<ttl>" lines of a +CIPPING response.
+// replyTime is given in units of 100 ms. The module reports a replyTime of 600000 and ttl of 255 for requests which timed out.
+func ParseCIPPINGResp(resp []string) ([]PingResult, error) {
+	results := make([]PingResult, 0)
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CIPPING:") {
+			continue
+		}
+		params := splitParams(strings.TrimPrefix(line, "+CIPPING:"))
+		if len(params) != 4 {
+			return results, fmt.Errorf("Malformed response to +CIPPING: \"%s\"", resp[i])
+		}
+		values := make([]int, 3)
+		for j, param := range []string{params[0], params[2], params[3]} {
+			v, err := strconv.Atoi(param)
+			if err != nil {
+				return results, fmt.Errorf("Malformed response to +CIPPING: \"%s\": %w", resp[i], err)
+			}
+			values[j] = v
+		}
+		result := PingResult{Seq: values[0], IP: params[1], TTL: values[2]}
+		if values[1] >= 600000 {
+			result.Timeout = true
+		} else {
+			result.RTT = time.Duration(values[1]) * 100 * time.Millisecond
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,93 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// Functionality levels settable with +CFUN
+const (
+	CFUNMinimum  = 0 // RF and SIM card disabled
+	CFUNFull     = 1
+	CFUNRadioOff = 4 // "flight mode", RF disabled but SIM card usable
+)
+
+// cfunTimeout is how long the module may take to change its functionality level
+const cfunTimeout = 10 * time.Second
+
+// cfunPollInterval is how often +CFUN? is polled while waiting for a level change
+var cfunPollInterval = 200 * time.Millisecond
+
+// SetRadio turns the radio on (+CFUN=1) or off (+CFUN=4) while keeping the module itself running
+func (s *sim7000e) SetRadio(on bool) error {
+	level := CFUNRadioOff
+	if on {
+		level = CFUNFull
+	}
+	return s.SetCFUN(level, false)
+}
+
+// SetCFUN sets the functionality level of the module with +CFUN.
+// If wait is true, +CFUN? is polled until the module reports the new level.
+func (s *sim7000e) SetCFUN(level int, wait bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return SetCFUN(s.modem, level, wait)
+}
+
+// SetCFUN sets the functionality level of the module behind modem with +CFUN.
+// If wait is true, +CFUN? is polled until the module reports the new level,
+// for at most cfunTimeout.
+func SetCFUN(modem *at.AT, level int, wait bool) error {
+	cmd := fmt.Sprintf("+CFUN=%d", level)
+	if _, err := modem.Command(cmd, at.WithTimeout(cfunTimeout)); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, moduleError(err))
+	}
+	if !wait {
+		return nil
+	}
+	deadline := time.Now().Add(cfunTimeout)
+	for {
+		resp, err := modem.Command("+CFUN?")
+		if err == nil {
+			if current, err := ParseCFUNResp(resp); err == nil && current == level {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s failed: module did not reach level %d in %v", cmd, level, cfunTimeout)
+		}
+		time.Sleep(cfunPollInterval)
+	}
+}
+
+// GetRadioState returns true if the module reports full functionality with +CFUN?
+func (s *sim7000e) GetRadioState() (bool, error) {
+	resp, err := s.Command("+CFUN?")
+	if err != nil {
+		return false, fmt.Errorf("+CFUN? failed: %w", err)
+	}
+	level, err := ParseCFUNResp(resp)
+	if err != nil {
+		return false, err
+	}
+	return level == CFUNFull, nil
+}
+
+// ParseCFUNResp returns the functionality level from a "+CFUN: <fun>" response
+func ParseCFUNResp(resp []string) (int, error) {
+	line, found := Response(resp).Line("+CFUN:")
+	if !found {
+		return 0, errors.New("Response to +CFUN? did not contain +CFUN:")
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "+CFUN:")))
+	if err != nil {
+		return 0, fmt.Errorf("Malformed response to +CFUN?: \"%s\"", line)
+	}
+	return level, nil
+}
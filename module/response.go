@@ -0,0 +1,53 @@
+package module
+
+import "strings"
+
+// Response is the lines of a response from the module, e.g. Response(lines) for the lines
+// returned by Command. Those don't contain the final result code, which is reported
+// through the error instead.
+type Response []string
+
+// Lines returns the response as plain lines
+func (r Response) Lines() []string {
+	return []string(r)
+}
+
+// HasError returns true, and the error given by the module, if the response
+// contains an "ERROR", "+CME ERROR: <err>" or "+CMS ERROR: <err>" line.
+// It is meant for raw lines read from the module, as Command reports errors through its error instead.
+func (r Response) HasError() (bool, string) {
+	for _, line := range r {
+		line = strings.TrimSpace(line)
+		if line == "ERROR" {
+			return true, line
+		}
+		for _, prefix := range []string{"+CME ERROR:", "+CMS ERROR:"} {
+			if strings.HasPrefix(line, prefix) {
+				return true, strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+	}
+	return false, ""
+}
+
+// Line returns the first line starting with prefix, with surrounding whitespace removed
+func (r Response) Line(prefix string) (string, bool) {
+	for _, line := range r {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, prefix) {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// Fields returns the comma separated, unquoted parameters of the first line starting with prefix,
+// e.g. Fields("+CSQ") returns "20" and "0" for "+CSQ: 20,0"
+func (r Response) Fields(prefix string) ([]string, bool) {
+	line, found := r.Line(prefix)
+	if !found {
+		return nil, false
+	}
+	value := strings.TrimPrefix(strings.TrimPrefix(line, prefix), ":")
+	return splitParams(value), true
+}
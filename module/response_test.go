@@ -0,0 +1,51 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResponseHelpers(t *testing.T) {
+	resp := Response(inputAsLines(`+CNACT: 1,"10.170.42.7"`))
+	if failed, _ := resp.HasError(); failed {
+		t.Fatal(`HasError returned true for a successful response`)
+	}
+	line, found := resp.Line("+CNACT:")
+	if !found || line != `+CNACT: 1,"10.170.42.7"` {
+		t.Fatalf(`Got line %q, %v`, line, found)
+	}
+	fields, found := resp.Fields("+CNACT")
+	if !found || strings.Join(fields, "|") != "1|10.170.42.7" {
+		t.Fatalf(`Got fields %q, %v`, fields, found)
+	}
+	if _, found := resp.Line("+CSQ:"); found {
+		t.Fatal(`Line found a prefix which isn't in the response`)
+	}
+	if _, found := resp.Fields("+CSQ"); found {
+		t.Fatal(`Fields found a prefix which isn't in the response`)
+	}
+	if len(resp.Lines()) != len(resp) {
+		t.Fatalf(`Lines returned %d lines, wanted %d`, len(resp.Lines()), len(resp))
+	}
+}
+
+func TestResponseHasError(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		failed  bool
+		message string
+	}{
+		"plain error": {input: "ERROR", failed: true, message: "ERROR"},
+		"CME error":   {input: "+CME ERROR: SIM not inserted", failed: true, message: "SIM not inserted"},
+		"CMS error":   {input: "+CMS ERROR: 500", failed: true, message: "500"},
+		"ok":          {input: "+CSQ: 20,0\n\nOK"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			failed, message := Response(inputAsLines(tc.input)).HasError()
+			if failed != tc.failed || message != tc.message {
+				t.Fatalf(`Got %v,%q, wanted %v,%q`, failed, message, tc.failed, tc.message)
+			}
+		})
+	}
+}
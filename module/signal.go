@@ -0,0 +1,41 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// RSSIUnknown is the RSSI reported by +CSQ when there is no signal or it cannot be detected
+const RSSIUnknown = 99
+
+// GetSignalQuality returns the received signal strength indication (0-31, or RSSIUnknown)
+// and bit error rate reported by +CSQ
+func (s *sim7000e) GetSignalQuality() (int, int, error) {
+	resp, err := s.Command("+CSQ")
+	if err != nil {
+		return RSSIUnknown, 0, fmt.Errorf("+CSQ failed: %w", err)
+	}
+	return ParseCSQResp(resp)
+}
+
+// ParseCSQResp parses the RSSI and BER from a "+CSQ: <rssi>,<ber>" response
+func ParseCSQResp(resp []string) (int, int, error) {
+	line, found := Response(resp).Line("+CSQ:")
+	if !found {
+		return RSSIUnknown, 0, errors.New("Response to +CSQ did not contain +CSQ:")
+	}
+	params, _ := Response(resp).Fields("+CSQ:")
+	if len(params) != 2 {
+		return RSSIUnknown, 0, fmt.Errorf("Malformed response to +CSQ: \"%s\"", line)
+	}
+	rssi, err := strconv.Atoi(params[0])
+	if err != nil {
+		return RSSIUnknown, 0, fmt.Errorf("Malformed RSSI in \"%s\": %w", line, err)
+	}
+	ber, err := strconv.Atoi(params[1])
+	if err != nil {
+		return RSSIUnknown, 0, fmt.Errorf("Malformed BER in \"%s\": %w", line, err)
+	}
+	return rssi, ber, nil
+}
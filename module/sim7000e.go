@@ -14,13 +14,48 @@ import (
 )
 
 type sim7000e struct {
-	modem *at.AT
-	port  io.ReadWriter
-	mutex sync.Mutex
+	modem        *at.AT
+	port         io.ReadWriter
+	mutex        sync.Mutex
+	commandDelay time.Duration
+	concatRef    byte
+
+	deliveryReportsEnabled bool
+	deliveryMutex          sync.Mutex
+	deliveries             map[int]chan int
+
+	watchdogStop chan struct{}
+	watchdogDone chan struct{}
 }
 
+var (
+	_ Module      = (*sim7000e)(nil)
+	_ Commander   = (*sim7000e)(nil)
+	_ Info        = (*sim7000e)(nil)
+	_ PDP         = (*sim7000e)(nil)
+	_ Radio       = (*sim7000e)(nil)
+	_ Pinger      = (*sim7000e)(nil)
+	_ SMS         = (*sim7000e)(nil)
+	_ USSD        = (*sim7000e)(nil)
+	_ PowerSaver  = (*sim7000e)(nil)
+	_ ConfigSaver = (*sim7000e)(nil)
+	_ Locator     = (*sim7000e)(nil)
+)
+
 // NewSIM7000 returns a ready to use Module
 func NewSIM7000(settings Settings) Module {
+	return newSIM7000(settings, false)
+}
+
+// NewAuto returns a ready to use Module like NewSIM7000,
+// but first queries the model with +CGMM and configures bands and
+// preferred mode according to the detected variant, unless Settings.ChatScript is set.
+// Unknown models are set up like NewSIM7000 would.
+func NewAuto(settings Settings) Module {
+	return newSIM7000(settings, true)
+}
+
+func newSIM7000(settings Settings, detectVariant bool) Module {
 	p, err := serial.New(serial.WithPort(settings.SerialPort), serial.WithBaud(115200))
 	if err != nil {
 		return nil
@@ -37,34 +72,129 @@ func NewSIM7000(settings Settings) Module {
 	s := new(sim7000e)
 	s.modem = modem
 	s.port = mio
+	switch {
+	case settings.CommandDelay == 0:
+		s.commandDelay = DefaultCommandDelay
+	case settings.CommandDelay > 0:
+		s.commandDelay = settings.CommandDelay
+	}
 
 	s.modem.Command("+CFUN=1,1", at.WithTimeout(30*time.Second))
 
+	if err := s.waitReady(); err != nil {
+		print(err)
+		return nil
+	}
 	s.modem.Init()
 
-	countdown(10, time.Second)
+	if err := s.configure(settings); err != nil {
+		print("Configuring module failed:", err)
+		return nil
+	}
+
+	variant := VariantUnknown
+	if detectVariant {
+		variant = s.detectVariant()
+	}
 
 	state := s.GetIPStatus()
 	switch state {
 	case IPStatus, IPClosed:
 		// already setup
 		print("Module already initialized!")
+		if settings.WatchdogInterval > 0 {
+			s.startWatchdog(settings.WatchdogInterval, settings.OnWatchdogChange)
+		}
 		return s
 	}
 	print("Initializing module...")
-	script := defaultChatScript(settings)
-	if settings.ChatScript != nil {
-		script = *settings.ChatScript
-	}
-	_, err = s.RunChatScript(script)
+	err = s.connect(chatScript(settings, variant), settings.MaxConnectionAttempts)
 	if err != nil {
 		println("Initialization script failed with error:", err.Error())
 		return nil
 	}
+	if settings.WatchdogInterval > 0 {
+		s.startWatchdog(settings.WatchdogInterval, settings.OnWatchdogChange)
+	}
 	return s
 }
 
+// signalPollInterval is how often connect checks for signal while there is none
+var signalPollInterval = 5 * time.Second
+
+// signalWaitTimeout is how long connect waits for signal before giving up
+var signalWaitTimeout = 3 * time.Minute
+
+// connect runs the script up to maxAttempts times until it succeeds.
+// Attempts are only made once the module reports signal, so time spent waiting for it
+// does not count against maxAttempts.
+func (s *sim7000e) connect(script ChatScript, maxAttempts int) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	deadline := time.Now().Add(signalWaitTimeout)
+	var err error
+	for attempt := 0; attempt < maxAttempts; {
+		rssi, _, csqErr := s.GetSignalQuality()
+		if csqErr != nil || rssi == RSSIUnknown {
+			if time.Now().After(deadline) {
+				return errors.New("No signal")
+			}
+			print("No signal yet, waiting...")
+			time.Sleep(signalPollInterval)
+			continue
+		}
+		attempt++
+		if _, err = s.RunChatScript(script); err == nil {
+			return nil
+		}
+		printf("Connection attempt %d/%d failed: %v\n", attempt, maxAttempts, err)
+		// return to IP INITIAL before starting over
+		s.Command("+CIPSHUT")
+	}
+	return err
+}
+
+// configure applies the settings which don't depend on the network
+func (s *sim7000e) configure(settings Settings) error {
+	echo := "E0"
+	if settings.Echo {
+		echo = "E1"
+	}
+	if _, err := s.Command(echo); err != nil {
+		return err
+	}
+	cmee := 2
+	switch settings.ErrorReporting {
+	case ErrorReportingNumeric:
+		cmee = 1
+	case ErrorReportingDisabled:
+		cmee = 0
+	}
+	if _, err := s.Command(fmt.Sprintf("+CMEE=%d", cmee)); err != nil {
+		return err
+	}
+	if settings.FlowControl == FlowControlHardware {
+		if _, err := s.Command("+IFC=2,2"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveConfig saves the current user profile to the module's NVRAM with AT&W, so that it survives
+// power cycles. The profile includes command echo (ATE), error reporting (+CMEE) and flow control
+// (+IFC) as applied from Settings. Network mode and band selection (+CNMP, +CMNB, +CBANDCFG)
+// are stored by the module as soon as they are set and need no saving.
+func (s *sim7000e) SaveConfig() error {
+	if _, err := s.Command("&W"); err != nil {
+		return fmt.Errorf("&W failed: %w", err)
+	}
+	return nil
+}
+
 func (s *sim7000e) Close() {
+	s.stopWatchdog()
 	s.Command("+CIPCLOSE")
 	resp, err := s.Command("+CIPSHUT")
 	_ = resp
@@ -83,23 +213,53 @@ func constructCSTT(apn, username, password string) string {
 	return fmt.Sprintf(`+CSTT="%s","%s","%s"`, apn, username, password)
 }
 
-func defaultChatScript(settings Settings) ChatScript {
+// constructCGAUTH returns the command configuring authentication for context cid,
+// or an empty string if there are no credentials to authenticate with
+func constructCGAUTH(cid int, auth AuthType, username, password string) string {
+	if username == "" && password == "" {
+		return ""
+	}
+	var protocol int
+	switch auth {
+	case AuthNone:
+		protocol = 0
+	case AuthPAP:
+		protocol = 1
+	case AuthCHAP:
+		protocol = 2
+	default:
+		protocol = 3
+	}
+	return fmt.Sprintf(`+CGAUTH=%d,%d,"%s","%s"`, cid, protocol, username, password)
+}
+
+// DefaultChatScript returns the script NewSIM7000 uses to connect the module to the network
+// unless Settings.ChatScript is set.
+// It can be used as a starting point for a custom script, e.g. to insert commands before +CIICR.
+func DefaultChatScript(settings Settings) ChatScript {
+	commands := []CommandResponse{
+		// no signal is common right after a cold start
+		CommandWithRetries("+CSQ", "+CSQ: ", 100*time.Millisecond, 10),
+		NormalCommandResponse("+CPIN?", "+CPIN: READY"),
+		NormalCommandResponse("+CIPRXGET=1", "OK"),
+		NormalCommandResponse("+CSTT?", "+CSTT: "),
+		NormalCommandResponse("+CIPSTATUS", "STATE: IP INITIAL"),
+	}
+	if cgauth := constructCGAUTH(1, settings.AuthType, settings.Username, settings.Password); cgauth != "" {
+		commands = append(commands, NormalCommandResponse(cgauth, "OK"))
+	}
 	return ChatScript{
-		Aborts: []string{"ERROR", "BUSY", "NO CARRIER", "+CSQ: 99,99"},
-		Commands: []CommandResponse{
-			NormalCommandResponse("+CSQ", "+CSQ: "),
-			NormalCommandResponse("+CPIN?", "+CPIN: READY"),
-			NormalCommandResponse("+CIPRXGET=1", "OK"),
-			NormalCommandResponse("+CSTT?", "+CSTT: "),
-			NormalCommandResponse("+CIPSTATUS", "STATE: IP INITIAL"),
+		Aborts:          []string{"ERROR", "NO CARRIER"},
+		RetryableAborts: []string{"BUSY", "+CSQ: 99,99"},
+		Commands: append(commands,
 			NormalCommandResponse(constructCSTT(settings.APN, settings.Username, settings.Password), "OK"),
 			NormalCommandResponse("+CSTT?", fmt.Sprintf(`+CSTT: "%s"`, settings.APN)),
 			NormalCommandResponse("+CIPSTATUS", "STATE: IP START"),
-			CommandResponse{"+CIICR", "", 30 * time.Second, 0},
+			LongCommand("+CIICR", ""),
 			NormalCommandResponse("+CIPSTATUS", "STATE: IP GPRSACT"),
 			NormalCommandResponse("+CIFSR", ""),
 			NormalCommandResponse("+CIPSTATUS", "STATE: IP STATUS"),
-		},
+		),
 	}
 }
 
@@ -107,7 +267,40 @@ func (s *sim7000e) Command(cmd string) ([]string, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.modem.Command(cmd)
+	resp, err := s.modem.Command(cmd)
+	return resp, moduleError(err)
+}
+
+func (s *sim7000e) commandWithTimeout(cmd string, timeout time.Duration) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resp, err := s.modem.Command(cmd, at.WithTimeout(timeout))
+	return resp, moduleError(err)
+}
+
+// SendATCommand issues cmd and reports whether any line of the response contains expected.
+// The final result code isn't part of the response lines, so an empty expected or "OK"
+// matches any command which didn't fail.
+func (s *sim7000e) SendATCommand(cmd string, timeout time.Duration, expected string) (bool, error) {
+	resp, err := s.SendATCommandReturnResponse(cmd, timeout)
+	if err != nil {
+		return false, err
+	}
+	if expected == "" || expected == "OK" {
+		return true, nil
+	}
+	for _, line := range resp {
+		if strings.Contains(line, expected) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SendATCommandReturnResponse issues cmd, waiting at most timeout for it to complete, and returns the response lines
+func (s *sim7000e) SendATCommandReturnResponse(cmd string, timeout time.Duration) ([]string, error) {
+	return s.commandWithTimeout(cmd, timeout)
 }
 
 func (s *sim7000e) Write(buffer []byte) (int, error) {
@@ -121,10 +314,63 @@ func (s *sim7000e) Read(buffer []byte) (int, error) {
 	return s.port.Read(buffer)
 }
 
+// probeTimeout is how long the module is given to answer each probe sent by probe
+var probeTimeout = 500 * time.Millisecond
+
+// maxProbes limits how many probes probe sends before giving up
+const maxProbes = 5
+
+// probe sends a bare "AT" until answered tells it's done with the response, at most maxProbes times.
+// It returns whether that happened. It must be called with the mutex held.
+func (s *sim7000e) probe(answered func(resp []string, err error) bool) bool {
+	for i := 0; i < maxProbes; i++ {
+		if answered(s.modem.Command("", at.WithTimeout(probeTimeout))) {
+			return true
+		}
+	}
+	return false
+}
+
+// readyTimeout is how long the module may take to start answering after a reset
+var readyTimeout = 30 * time.Second
+
+// waitReady probes the module until it answers, for at most readyTimeout
+func (s *sim7000e) waitReady() error {
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		answered := s.probe(func(resp []string, err error) bool {
+			return err == nil
+		})
+		if answered {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Module did not become ready in %v", readyTimeout)
+		}
+	}
+}
+
+// Flush discards any unsolicited lines the module has sent since the previous command,
+// so that they aren't mistaken for the response of the next one.
+// Pending lines are consumed as the response of a bare "AT", which is repeated
+// until the module answers it with nothing but OK.
+func (s *sim7000e) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	clean := s.probe(func(resp []string, err error) bool {
+		return err == nil && len(resp) == 0
+	})
+	if !clean {
+		return fmt.Errorf("Flush failed: module still sending unsolicited data after %d probes", maxProbes)
+	}
+	return nil
+}
+
 func (s *sim7000e) RunChatScript(script ChatScript) ([]string, error) {
-	containsAbortTerm := func(response []string) bool {
+	containsTerm := func(response []string, terms []string) bool {
 		for i := 0; i < len(response); i++ {
-			for _, term := range script.Aborts {
+			for _, term := range terms {
 				if strings.Contains(response[i], term) {
 					return true
 				}
@@ -132,22 +378,37 @@ func (s *sim7000e) RunChatScript(script ChatScript) ([]string, error) {
 		}
 		return false
 	}
+	retryDelay := script.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = DefaultRetryDelay
+	}
 	output := make([]string, 0)
 	retriesLeft := 0
 	for i := range script.Commands {
 		retriesLeft = script.Commands[i].Retries
 	tryAtCommand:
-		time.Sleep(time.Second)
+		time.Sleep(s.commandDelay)
 		resp, err := s.modem.Command(script.Commands[i].Command, at.WithTimeout(script.Commands[i].Timeout))
 		if err != nil {
 			retriesLeft--
 			if retriesLeft > 0 {
 				goto tryAtCommand
 			}
-			return output, err
+			return output, moduleError(err)
 		}
 		output = append(output, resp...)
-		if containsAbortTerm(resp) {
+		if script.OnResponse != nil {
+			script.OnResponse(script.Commands[i].Command, resp)
+		}
+		if containsTerm(resp, script.RetryableAborts) {
+			retriesLeft--
+			if retriesLeft > 0 {
+				time.Sleep(retryDelay)
+				goto tryAtCommand
+			}
+			return output, errors.New("Reply contained abort term")
+		}
+		if containsTerm(resp, script.Aborts) {
 			return output, errors.New("Reply contained abort term")
 		}
 		if script.Commands[i].Response == "" {
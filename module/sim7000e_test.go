@@ -0,0 +1,678 @@
+package module
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/internal/fakemodem"
+)
+
+func newTestSIM7000(m *fakemodem.Modem) *sim7000e {
+	return &sim7000e{
+		modem: at.New(m, at.WithTimeout(time.Second)),
+		port:  m,
+	}
+}
+
+func TestDetectVariantSIM7000E(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CGMI", "SIMCOM INC.\nOK")
+	m.Reply("+CGMM", "SIMCOM_SIM7000E\nOK")
+	m.Reply("+CGMR", "Revision:1351B03SIM7000E\nOK")
+
+	s := newTestSIM7000(m)
+	v := s.detectVariant()
+	if v != VariantSIM7000E {
+		t.Fatalf(`Got %v, wanted %v`, v, VariantSIM7000E)
+	}
+
+	script := withVariantCommands(DefaultChatScript(Settings{APN: "internet"}), v)
+	found := false
+	for _, cmd := range script.Commands {
+		if cmd.Command == `+CBANDCFG="NB-IOT",3,8,20` {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal(`Chat script does not configure SIM7000E bands`)
+	}
+}
+
+func TestChatScriptKeepsUserScript(t *testing.T) {
+	user := ChatScript{Commands: []CommandResponse{NormalCommandResponse("+CIICR", "")}}
+	got := chatScript(Settings{APN: "internet", ChatScript: &user}, VariantSIM7000E)
+	if len(got.Commands) != 1 || got.Commands[0].Command != "+CIICR" {
+		t.Fatalf(`Got commands %v, wanted the user's script unchanged`, got.Commands)
+	}
+
+	got = chatScript(Settings{APN: "internet"}, VariantSIM7000E)
+	if len(got.Commands) <= len(DefaultChatScript(Settings{APN: "internet"}).Commands) {
+		t.Fatal(`Default chat script does not configure the variant`)
+	}
+}
+
+func TestDetectVariantUnknownModel(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CGMM", "SIMCOM_SIM7080G\nOK")
+
+	s := newTestSIM7000(m)
+	v := s.detectVariant()
+	if v != VariantUnknown {
+		t.Fatalf(`Got %v, wanted %v`, v, VariantUnknown)
+	}
+	script := DefaultChatScript(Settings{APN: "internet"})
+	if got := withVariantCommands(script, v); len(got.Commands) != len(script.Commands) {
+		t.Fatal(`Unknown variant should use the generic chat script`)
+	}
+}
+
+func TestConstructCGAUTH(t *testing.T) {
+	tests := map[string]struct {
+		auth AuthType
+		want string
+	}{
+		"unset defaults to auto": {auth: AuthType(0), want: `+CGAUTH=1,3,"user","pass"`},
+		"auto":                   {auth: AuthAuto, want: `+CGAUTH=1,3,"user","pass"`},
+		"none":                   {auth: AuthNone, want: `+CGAUTH=1,0,"user","pass"`},
+		"PAP":                    {auth: AuthPAP, want: `+CGAUTH=1,1,"user","pass"`},
+		"CHAP":                   {auth: AuthCHAP, want: `+CGAUTH=1,2,"user","pass"`},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := constructCGAUTH(1, tc.auth, "user", "pass"); got != tc.want {
+				t.Fatalf(`Got %s, wanted %s`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaultChatScriptAuthentication(t *testing.T) {
+	containsCGAUTH := func(script ChatScript) bool {
+		for _, cmd := range script.Commands {
+			if strings.HasPrefix(cmd.Command, "+CGAUTH=") {
+				return true
+			}
+		}
+		return false
+	}
+	if containsCGAUTH(DefaultChatScript(Settings{APN: "internet"})) {
+		t.Fatal(`+CGAUTH issued without credentials`)
+	}
+	if !containsCGAUTH(DefaultChatScript(Settings{APN: "internet", Username: "user", Password: "pass", AuthType: AuthCHAP})) {
+		t.Fatal(`+CGAUTH not issued with credentials`)
+	}
+}
+
+func TestSetRadio(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	s := newTestSIM7000(m)
+
+	if err := s.SetRadio(false); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if err := s.SetRadio(true); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"+CFUN=4", "+CFUN=1"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestGetRadioState(t *testing.T) {
+	tests := map[string]struct {
+		reply string
+		want  bool
+	}{
+		"full":      {reply: "+CFUN: 1\nOK", want: true},
+		"radio off": {reply: "+CFUN: 4\nOK", want: false},
+		"minimum":   {reply: "+CFUN: 0\nOK", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			m.Reply("+CFUN?", tc.reply)
+			got, err := newTestSIM7000(m).GetRadioState()
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %v, wanted %v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunChatScriptRetryableAbort(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 99,99\nOK", "+CSQ: 20,0\nOK")
+
+	script := ChatScript{
+		Aborts:          []string{"ERROR"},
+		RetryableAborts: []string{"+CSQ: 99,99"},
+		RetryDelay:      10 * time.Millisecond,
+		Commands:        []CommandResponse{{"+CSQ", "+CSQ: ", 100 * time.Millisecond, 3}},
+	}
+	if _, err := newTestSIM7000(m).RunChatScript(script); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got := m.Commands(); len(got) != 2 {
+		t.Fatalf(`Got commands %q, wanted +CSQ twice`, got)
+	}
+}
+
+func TestRunChatScriptRetryableAbortExhausted(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 99,99\nOK")
+
+	script := ChatScript{
+		RetryableAborts: []string{"+CSQ: 99,99"},
+		RetryDelay:      10 * time.Millisecond,
+		Commands:        []CommandResponse{{"+CSQ", "+CSQ: ", 100 * time.Millisecond, 2}},
+	}
+	if _, err := newTestSIM7000(m).RunChatScript(script); err == nil {
+		t.Fatal(`Expected an error once retries are exhausted`)
+	}
+}
+
+func TestRunChatScriptOnResponse(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CGSN", "869951031234567\nOK")
+	m.Reply("+CSQ", "+CSQ: 20,0\nOK")
+
+	got := make(map[string][]string)
+	script := ChatScript{
+		Commands: []CommandResponse{
+			NormalCommandResponse("+CGSN", ""),
+			NormalCommandResponse("+CSQ", "+CSQ: "),
+		},
+		OnResponse: func(cmd string, resp []string) {
+			got[cmd] = resp
+		},
+	}
+	if _, err := newTestSIM7000(m).RunChatScript(script); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+
+	want := map[string]string{
+		"+CGSN": "869951031234567",
+		"+CSQ":  "+CSQ: 20,0",
+	}
+	for cmd, line := range want {
+		if len(got[cmd]) != 1 || got[cmd][0] != line {
+			t.Errorf(`Got response %q for %s, wanted %q`, got[cmd], cmd, line)
+		}
+	}
+}
+
+func TestConnectWaitsForSignal(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 99,99\nOK", "+CSQ: 18,0\nOK")
+	old := signalPollInterval
+	signalPollInterval = 10 * time.Millisecond
+	defer func() { signalPollInterval = old }()
+
+	script := ChatScript{
+		Aborts:   []string{"ERROR"},
+		Commands: []CommandResponse{NormalCommandResponse("+CIICR", "")},
+	}
+	if err := newTestSIM7000(m).connect(script, 1); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"+CSQ", "+CSQ", "+CIICR"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestConnectCountsFailedAttempts(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 18,0\nOK")
+	m.Reply("+CIICR", "ERROR", "OK")
+
+	script := ChatScript{Commands: []CommandResponse{NormalCommandResponse("+CIICR", "")}}
+	if err := newTestSIM7000(m).connect(script, 2); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"+CSQ", "+CIICR", "+CIPSHUT", "+CSQ", "+CIICR"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestSendUSSD(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply(`+CUSD=1,"*100#",15`, "OK\n\n+CUSD: 0,\"Balance: 5.00 EUR\",15")
+
+	got, err := newTestSIM7000(m).SendUSSD("*100#")
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got != "Balance: 5.00 EUR" {
+		t.Fatalf(`Got %q, wanted "Balance: 5.00 EUR"`, got)
+	}
+}
+
+func TestWatchdogReportsTransitions(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CPIN?", "+CPIN: READY\nOK", "+CPIN: READY\nOK", "+CME ERROR: 10")
+	m.Reply("+CGATT?", "+CGATT: 1\nOK", "+CGATT: 0\nOK")
+
+	changes := make(chan [2]WatchdogState, 10)
+	s := newTestSIM7000(m)
+	s.startWatchdog(10*time.Millisecond, func(previous, current WatchdogState) {
+		changes <- [2]WatchdogState{previous, current}
+	})
+
+	want := [][2]WatchdogState{
+		{{SIMReady: true, Attached: true}, {SIMReady: true, Attached: false}},
+		{{SIMReady: true, Attached: false}, {SIMReady: false, Attached: false}},
+	}
+	for i := range want {
+		select {
+		case got := <-changes:
+			if got != want[i] {
+				t.Fatalf(`Change %d: got %+v, wanted %+v`, i, got, want[i])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf(`Change %d was not reported`, i)
+		}
+	}
+
+	s.Close()
+	n := len(m.Commands())
+	time.Sleep(50 * time.Millisecond)
+	if len(m.Commands()) != n {
+		t.Fatal(`Watchdog kept running after Close`)
+	}
+	select {
+	case got := <-changes:
+		t.Fatalf(`Unexpected change %+v`, got)
+	default:
+	}
+}
+
+func TestConfigureErrorReporting(t *testing.T) {
+	tests := map[string]struct {
+		mode ErrorReporting
+		want string
+	}{
+		"default":  {want: "+CMEE=2"},
+		"numeric":  {mode: ErrorReportingNumeric, want: "+CMEE=1"},
+		"disabled": {mode: ErrorReportingDisabled, want: "+CMEE=0"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			if err := newTestSIM7000(m).configure(Settings{ErrorReporting: tc.mode}); err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got := m.Commands(); len(got) != 2 || got[1] != tc.want {
+				t.Fatalf(`Got commands %q, wanted E0 and %q`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCommandReturnsCMEError(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CPIN?", "+CME ERROR: SIM not inserted")
+
+	_, err := newTestSIM7000(m).Command("+CPIN?")
+	var cme *CMEError
+	if !errors.As(err, &cme) {
+		t.Fatalf(`Got %v, wanted a *CMEError`, err)
+	}
+	if cme.Code != 10 {
+		t.Fatalf(`Got code %d, wanted 10`, cme.Code)
+	}
+}
+
+func TestRunChatScriptReturnsCMEError(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CPIN?", "+CME ERROR: SIM not inserted")
+
+	script := ChatScript{Commands: []CommandResponse{NormalCommandResponse("+CPIN?", "READY")}}
+	_, err := newTestSIM7000(m).RunChatScript(script)
+	var cme *CMEError
+	if !errors.As(err, &cme) || cme.Code != 10 {
+		t.Fatalf(`Got %v, wanted a *CMEError with code 10`, err)
+	}
+}
+
+func TestFlushDiscardsStaleLines(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 20,0\nOK")
+	s := newTestSIM7000(m)
+
+	m.Inject("+CMTI: \"SM\",1\nRDY\n")
+	if err := s.Flush(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+
+	resp, err := s.Command("+CSQ")
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(resp) != 1 || resp[0] != "+CSQ: 20,0" {
+		t.Fatalf(`Got %q, wanted only the +CSQ response`, resp)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	oldProbe, oldReady := probeTimeout, readyTimeout
+	probeTimeout = 10 * time.Millisecond
+	defer func() { probeTimeout, readyTimeout = oldProbe, oldReady }()
+
+	m := fakemodem.New()
+	defer m.Close()
+	// still booting for more than one round of probes
+	replies := make([]string, maxProbes+2)
+	m.Reply("", append(replies, "OK")...)
+	s := newTestSIM7000(m)
+
+	if err := s.waitReady(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got := len(m.Commands()); got != maxProbes+3 {
+		t.Fatalf(`Module got %d probes, wanted %d`, got, maxProbes+3)
+	}
+
+	readyTimeout = 0
+	m.Reply("", "")
+	if err := s.waitReady(); err == nil {
+		t.Fatal(`Expected an error from a module which never answers`)
+	}
+}
+
+func TestSleepAndWake(t *testing.T) {
+	old := probeTimeout
+	probeTimeout = 50 * time.Millisecond
+	defer func() { probeTimeout = old }()
+
+	m := fakemodem.New()
+	defer m.Close()
+	// the first probe is lost while the module wakes up
+	m.Reply("", "", "OK")
+	s := newTestSIM7000(m)
+
+	if err := s.Sleep(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if err := s.Wake(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := "+CSCLK=1|||+CSCLK=0"
+	if got := strings.Join(m.Commands(), "|"); got != want {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestWakeGivesUp(t *testing.T) {
+	old := probeTimeout
+	probeTimeout = 10 * time.Millisecond
+	defer func() { probeTimeout = old }()
+
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("", "")
+	s := newTestSIM7000(m)
+
+	if err := s.Wake(); err == nil {
+		t.Fatal(`Expected an error from a module which never answers`)
+	}
+	if got := len(m.Commands()); got != maxProbes {
+		t.Fatalf(`Module got %d probes, wanted %d`, got, maxProbes)
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	s := newTestSIM7000(m)
+
+	if err := s.SaveConfig(); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got := m.Commands(); len(got) != 1 || got[0] != "&W" {
+		t.Fatalf(`Got commands %q, wanted "&W"`, got)
+	}
+
+	m.Reply("&W", "ERROR")
+	if err := s.SaveConfig(); err == nil {
+		t.Fatal(`Expected an error when the module rejects &W`)
+	}
+}
+
+func TestRunChatScriptCommandDelay(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+
+	s := newTestSIM7000(m)
+	s.commandDelay = 50 * time.Millisecond
+	script := ChatScript{
+		Commands: []CommandResponse{
+			NormalCommandResponse("+CPIN?", ""),
+			NormalCommandResponse("+CSQ", ""),
+			NormalCommandResponse("+CGATT?", ""),
+		},
+	}
+
+	start := time.Now()
+	if _, err := s.RunChatScript(script); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	elapsed := time.Since(start)
+	if elapsed < 3*s.commandDelay || elapsed >= DefaultCommandDelay {
+		t.Fatalf(`Script took %v, wanted about %v`, elapsed, 3*s.commandDelay)
+	}
+}
+
+func TestSetCFUNWaitsForLevel(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CFUN?", "+CFUN: 1\nOK", "+CFUN: 0\nOK")
+
+	if err := newTestSIM7000(m).SetCFUN(CFUNMinimum, true); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"+CFUN=0", "+CFUN?", "+CFUN?"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestConfigureHardwareFlowControl(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+
+	if err := newTestSIM7000(m).configure(Settings{FlowControl: FlowControlHardware}); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"E0", "+CMEE=2", "+IFC=2,2"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+}
+
+func TestSendATCommand(t *testing.T) {
+	tests := map[string]struct {
+		reply    string
+		expected string
+		want     bool
+		wantErr  bool
+	}{
+		"match":       {reply: "+CPIN: READY\nOK", expected: "READY", want: true},
+		"no match":    {reply: "+CPIN: SIM PIN\nOK", expected: "READY", want: false},
+		"ok":          {reply: "OK", expected: "OK", want: true},
+		"error":       {reply: "ERROR", expected: "READY", wantErr: true},
+		"no response": {reply: "", expected: "READY", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			m.Reply("+CPIN?", tc.reply)
+
+			got, err := newTestSIM7000(m).SendATCommand("+CPIN?", 50*time.Millisecond, tc.expected)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf(`Got error %v, wanted error: %v`, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %v, wanted %v`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigureEcho(t *testing.T) {
+	tests := map[string]struct {
+		echo bool
+		want string
+	}{
+		"disabled by default": {want: "E0"},
+		"kept enabled":        {echo: true, want: "E1"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := fakemodem.New()
+			defer m.Close()
+			if err := newTestSIM7000(m).configure(Settings{Echo: tc.echo}); err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got := m.Commands(); len(got) == 0 || got[0] != tc.want {
+				t.Fatalf(`Got commands %q, wanted %q first`, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetAPNFallsBackToPDPContext(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSTT?", `+CSTT: "","",""`+"\nOK")
+	m.Reply("+CGDCONT?", `+CGDCONT: 1,"IP","iot.example","0.0.0.0",0,0,0,0`+"\nOK")
+
+	got, err := newTestSIM7000(m).GetAPN()
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got != "iot.example" {
+		t.Fatalf(`Got %q, wanted iot.example`, got)
+	}
+}
+
+func TestSendSMSPDU(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CMGS=22", ">")
+	m.ReplyRaw("+CMGS: 5\nOK")
+
+	if err := newTestSIM7000(m).SendSMSPDU("+31641600986", "hellohello"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := []string{"+CMGF=0", "+CMGS=22"}
+	if got := m.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, got, want)
+	}
+	if got := string(m.Raw()); got != "0001000B911346610089F600000AE8329BFD4697D9EC37\x1a" {
+		t.Fatalf(`Got PDU %q`, got)
+	}
+}
+
+func TestWaitForDelivery(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CMGS=22", ">")
+	m.ReplyRaw("+CMGS: 42\nOK")
+	s := newTestSIM7000(m)
+
+	refs, err := s.SendSMSPDUWithReport("+31641600986", "hellohello")
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(refs) != 1 || refs[0] != 42 {
+		t.Fatalf(`Got references %v, wanted [42]`, refs)
+	}
+	if !strings.HasPrefix(string(m.Raw()), "0021") {
+		t.Fatalf(`Status report not requested in PDU %q`, m.Raw())
+	}
+	if got := m.Commands(); len(got) == 0 || got[0] != "+CNMI=2,1,0,1,0" {
+		t.Fatalf(`Got commands %q, wanted +CNMI=2,1,0,1,0 first`, got)
+	}
+
+	// a report for another message, one telling the service center is still trying, then the final one
+	m.Inject("+CDS: 25\n07911326040000F006070B911346610089F6212071211374402120712113054000")
+	m.Inject("+CDS: 25\n07911326040000F0062A0B911346610089F6212071211374402120712113054030")
+	m.Inject("+CDS: 25\n07911326040000F0062A0B911346610089F6212071211374402120712113054000")
+
+	delivered, err := s.WaitForDelivery(42, time.Second)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if !delivered {
+		t.Fatal(`Message not reported delivered`)
+	}
+	if len(s.deliveries) != 0 {
+		t.Fatalf(`Got %d buffered delivery report channels after waiting, wanted none`, len(s.deliveries))
+	}
+}
+
+func TestDeliveryReportsOfReusedReference(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CMGS=22", ">")
+	m.ReplyRaw("+CMGS: 42\nOK")
+	s := newTestSIM7000(m)
+
+	if _, err := s.WaitForDelivery(42, time.Second); err == nil {
+		t.Fatal(`Expected an error waiting for a message which wasn't sent`)
+	}
+
+	if _, err := s.SendSMSPDUWithReport("+31641600986", "hellohello"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	// a failed delivery of the first message with reference 42
+	m.Inject("+CDS: 25\n07911326040000F0062A0B911346610089F6212071211374402120712113054041")
+	// reports for messages nobody waits for are not kept
+	m.Inject("+CDS: 25\n07911326040000F006070B911346610089F6212071211374402120712113054000")
+	time.Sleep(50 * time.Millisecond)
+	s.deliveryMutex.Lock()
+	_, kept := s.deliveries[7]
+	s.deliveryMutex.Unlock()
+	if kept {
+		t.Fatal(`Report for an unknown message was buffered`)
+	}
+
+	// sending again with the same reference discards the stale report
+	if _, err := s.SendSMSPDUWithReport("+31641600986", "hellohello"); err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	m.Inject("+CDS: 25\n07911326040000F0062A0B911346610089F6212071211374402120712113054000")
+	delivered, err := s.WaitForDelivery(42, time.Second)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if !delivered {
+		t.Fatal(`Got the stale report of the earlier message`)
+	}
+}
@@ -0,0 +1,35 @@
+package module
+
+import "fmt"
+
+// Sleep enables slow clock mode with +CSCLK=1, letting the module sleep whenever DTR is high.
+// The serial package can't drive DTR, so it must be controlled by other means,
+// e.g. a GPIO wired to the DTR pin of the module.
+func (s *sim7000e) Sleep() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.modem.Command("+CSCLK=1"); err != nil {
+		return fmt.Errorf("+CSCLK=1 failed: %w", moduleError(err))
+	}
+	return nil
+}
+
+// Wake brings the module out of sleep and disables slow clock mode with +CSCLK=0.
+// DTR must have been pulled low before calling Wake. The module may miss the first
+// characters sent while it is waking up, so a bare "AT" is repeated until it answers.
+func (s *sim7000e) Wake() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	awake := s.probe(func(resp []string, err error) bool {
+		return err == nil
+	})
+	if !awake {
+		return fmt.Errorf("Wake failed: module did not answer %d probes", maxProbes)
+	}
+	if _, err := s.modem.Command("+CSCLK=0"); err != nil {
+		return fmt.Errorf("+CSCLK=0 failed: %w", moduleError(err))
+	}
+	return nil
+}
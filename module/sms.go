@@ -0,0 +1,106 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+)
+
+// GetSMSC returns the SMS service center number configured with +CSCA
+func (s *sim7000e) GetSMSC() (string, error) {
+	resp, err := s.Command("+CSCA?")
+	if err != nil {
+		return "", fmt.Errorf("+CSCA? failed: %w", err)
+	}
+	return ParseCSCAResp(resp)
+}
+
+// SetSMSC configures the SMS service center number, given in international format ("+358...") or national format
+func (s *sim7000e) SetSMSC(number string) error {
+	cmd, err := constructCSCA(number)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Command(cmd); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd, err)
+	}
+	return nil
+}
+
+// smsSendTimeout is how long the module may take to send each SMS
+var smsSendTimeout = 60 * time.Second
+
+// SendSMSPDU sends message to number in PDU mode, which unlike text mode supports
+// any Unicode characters (as UCS2) and messages too long for a single SMS,
+// which are sent as a concatenated message.
+func (s *sim7000e) SendSMSPDU(number, message string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := s.sendSMSPDU(number, message, false)
+	return err
+}
+
+// sendSMSPDU sends message to number in PDU mode and returns the message references of its parts.
+// It must be called with the mutex held.
+func (s *sim7000e) sendSMSPDU(number, message string, statusReport bool) ([]int, error) {
+	s.concatRef++
+	pdus, err := encodeSMSSubmit(number, message, s.concatRef, statusReport)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.modem.Command("+CMGF=0"); err != nil {
+		return nil, fmt.Errorf("+CMGF=0 failed: %w", moduleError(err))
+	}
+	refs := make([]int, 0, len(pdus))
+	for i, pdu := range pdus {
+		cmd := fmt.Sprintf("+CMGS=%d", pdu.Length)
+		resp, err := s.modem.SMSCommand(cmd, pdu.Hex, at.WithTimeout(smsSendTimeout))
+		if err != nil {
+			return refs, fmt.Errorf("%s failed for part %d of %d: %w", cmd, i+1, len(pdus), moduleError(err))
+		}
+		ref, err := ParseCMGSResp(resp)
+		if err != nil {
+			return refs, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func constructCSCA(number string) (string, error) {
+	digits := strings.TrimPrefix(number, "+")
+	if digits == "" {
+		return "", errors.New("Empty SMS service center number")
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("Invalid SMS service center number \"%s\"", number)
+		}
+	}
+	// type of address: 145 for international numbers, 129 otherwise
+	toa := 129
+	if strings.HasPrefix(number, "+") {
+		toa = 145
+	}
+	return fmt.Sprintf(`+CSCA="%s",%d`, number, toa), nil
+}
+
+// ParseCSCAResp returns the service center number from a `+CSCA: "<number>",<type>` response
+func ParseCSCAResp(resp []string) (string, error) {
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CSCA:") {
+			continue
+		}
+		params := splitParams(strings.TrimPrefix(line, "+CSCA:"))
+		if len(params) != 2 {
+			return "", fmt.Errorf("Malformed response to +CSCA?: \"%s\"", resp[i])
+		}
+		return params[0], nil
+	}
+	return "", errors.New("Response to +CSCA? did not contain +CSCA:")
+}
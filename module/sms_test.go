@@ -0,0 +1,184 @@
+package module
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCSCAResponseParsing(t *testing.T) {
+	got, err := ParseCSCAResp(inputAsLines(`+CSCA: "+358405202000",145
+
+OK`))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if got != "+358405202000" {
+		t.Fatalf(`Got %s, wanted +358405202000`, got)
+	}
+	if _, err := ParseCSCAResp(inputAsLines("\nOK")); err == nil {
+		t.Fatal(`Expected an error for a response without +CSCA:`)
+	}
+}
+
+func TestConstructCSCA(t *testing.T) {
+	tests := map[string]struct {
+		number string
+		want   string
+	}{
+		"international": {number: "+358405202000", want: `+CSCA="+358405202000",145`},
+		"national":      {number: "0405202000", want: `+CSCA="0405202000",129`},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := constructCSCA(tc.number)
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %s, wanted %s`, got, tc.want)
+			}
+		})
+	}
+	if _, err := constructCSCA(`+358"`); err == nil {
+		t.Fatal(`Expected an error for an invalid number`)
+	}
+}
+
+func TestCUSDResponseParsing(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"GSM text": {
+			input: `+CUSD: 0,"Saldo: 5,00 EUR",15`,
+			want:  "Saldo: 5,00 EUR",
+		},
+		"UCS2 text": {
+			input: `+CUSD: 0,"00530061006C0064006F003A00200035002C00300030002020AC",72`,
+			want:  "Saldo: 5,00 €",
+		},
+		"further action required": {
+			input: `+CUSD: 1,"1. Balance 2. Bundles",15`,
+			want:  "1. Balance 2. Bundles",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCUSDResp([]string{tc.input})
+			if err != nil {
+				t.Fatalf(`Unexpected error: %v`, err)
+			}
+			if got != tc.want {
+				t.Fatalf(`Got %q, wanted %q`, got, tc.want)
+			}
+		})
+	}
+	if _, err := ParseCUSDResp([]string{`+CUSD: 4`}); err == nil {
+		t.Fatal(`Expected an error for unsupported operation`)
+	}
+}
+
+func TestEncodeSMSSubmitASCII(t *testing.T) {
+	pdus, err := encodeSMSSubmit("+31641600986", "hellohello", 0, false)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := "0001000B911346610089F600000AE8329BFD4697D9EC37"
+	if len(pdus) != 1 || pdus[0].Hex != want {
+		t.Fatalf(`Got %+v, wanted %s`, pdus, want)
+	}
+	if pdus[0].Length != len(want)/2-1 {
+		t.Fatalf(`Got TPDU length %d, wanted %d`, pdus[0].Length, len(want)/2-1)
+	}
+}
+
+func TestEncodeSMSSubmitExtensionCharacters(t *testing.T) {
+	pdus, err := encodeSMSSubmit("0401234567", "€5", 0, false)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	// national number, escape + 0x65 and '5' as three septets
+	want := "0001000A814010325476000003"
+	if len(pdus) != 1 || !strings.HasPrefix(pdus[0].Hex, want) {
+		t.Fatalf(`Got %+v, wanted prefix %s`, pdus, want)
+	}
+	if got := unpackSeptets(t, pdus[0].Hex[len(want):], 3, 0); string(got) != "\x1b\x655" {
+		t.Fatalf(`Got septets %q`, got)
+	}
+}
+
+func TestEncodeSMSSubmitUnicode(t *testing.T) {
+	pdus, err := encodeSMSSubmit("+8613800138000", "你好", 0, false)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	want := "0001000D91683108108300F00008044F60597D"
+	if len(pdus) != 1 || pdus[0].Hex != want {
+		t.Fatalf(`Got %+v, wanted %s`, pdus, want)
+	}
+}
+
+func TestEncodeSMSSubmitMultipart(t *testing.T) {
+	message := strings.Repeat("a", 161)
+	pdus, err := encodeSMSSubmit("+31641600986", message, 0x2a, false)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if len(pdus) != 2 {
+		t.Fatalf(`Got %d parts, wanted 2`, len(pdus))
+	}
+	header := "0041000B911346610089F60000"
+	decoded := ""
+	for i, pdu := range pdus {
+		septets := []int{153, 8}[i]
+		if !strings.HasPrefix(pdu.Hex, header) {
+			t.Fatalf(`Part %d: got %s, wanted prefix %s`, i+1, pdu.Hex, header)
+		}
+		rest := pdu.Hex[len(header):]
+		udl := fmt.Sprintf("%02X", septets+7)
+		udh := fmt.Sprintf("0500032A02%02X", i+1)
+		if !strings.HasPrefix(rest, udl+udh) {
+			t.Fatalf(`Part %d: got user data %s, wanted prefix %s`, i+1, rest, udl+udh)
+		}
+		for _, septet := range unpackSeptets(t, rest[len(udl+udh):], septets, 1) {
+			decoded += string(gsm7Alphabet[septet])
+		}
+	}
+	if decoded != message {
+		t.Fatalf(`Parts decode to %q, wanted %q`, decoded, message)
+	}
+}
+
+// unpackSeptets unpacks n septets from packed hex user data which starts after fillBits bits of padding
+func unpackSeptets(t *testing.T, packedHex string, n, fillBits int) []byte {
+	packed, err := hex.DecodeString(packedHex)
+	if err != nil {
+		t.Fatalf(`Invalid hex %q: %v`, packedHex, err)
+	}
+	septets := make([]byte, n)
+	for i := range septets {
+		for j := 0; j < 7; j++ {
+			bit := fillBits + 7*i + j
+			if packed[bit/8]&(1<<uint(bit%8)) != 0 {
+				septets[i] |= 1 << uint(j)
+			}
+		}
+	}
+	return septets
+}
+
+func TestStatusReportPDUParsing(t *testing.T) {
+	pdu := "07911326040000F0062A0B911346610089F6212071211374402120712113054000"
+	ref, status, err := ParseStatusReportPDU(pdu)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if ref != 0x2a || status != 0 {
+		t.Fatalf(`Got reference %d and status %d, wanted 42 and 0`, ref, status)
+	}
+	if _, _, err := ParseStatusReportPDU("07911326040000F0012A"); err == nil {
+		t.Fatal(`Expected an error for a PDU which isn't a status report`)
+	}
+}
@@ -1,6 +1,8 @@
 package module
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +25,7 @@ const (
 )
 
 func ParseCIPSTATUSResp(resp []string) CIPStatus {
+	resp = joinWrappedLines(resp, cipstatusPrefixes)
 	for i := 0; i < len(resp); i++ {
 		line := strings.TrimSpace(resp[i])
 		if strings.HasPrefix(line, "STATE:") {
@@ -55,3 +58,87 @@ func ParseCIPSTATUSResp(resp []string) CIPStatus {
 	}
 	return IPStatusUnknown
 }
+
+// String returns the +CIPSTATUS state name corresponding to the status,
+// so that ParseCIPSTATUSResp can parse it back into the same value.
+func (s CIPStatus) String() string {
+	switch s {
+	case IPInitial:
+		return "IP INITIAL"
+	case IPStart:
+		return "IP START"
+	case IPConfig:
+		return "IP CONFIG"
+	case IPGPRSAct:
+		return "IP GPRSACT"
+	case IPStatus:
+		return "IP STATUS"
+	case IPProcessing:
+		return "IP PROCESSING"
+	case IPConnectOK:
+		return "CONNECT OK"
+	case IPClosing:
+		return "TCP CLOSING"
+	case IPClosed:
+		return "TCP CLOSED"
+	case IPPDPDeact:
+		return "PDP DEACT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ConnInfo describes a single connection listed in the +CIPSTATUS response
+// when the module is in multi-connection mode (+CIPMUX=1)
+type ConnInfo struct {
+	Index    int
+	Bearer   int
+	Protocol string
+	RemoteIP string
+	Port     int
+	State    string
+}
+
+// ParseCIPSTATUSConnections parses the "C: <n>,<bearer>,<TCP/UDP>,<IP address>,<port>,<client state>"
+// lines from a +CIPSTATUS response given in CIPMUX=1 mode.
+// Records the module has wrapped over several lines are joined back together before parsing.
+func ParseCIPSTATUSConnections(resp []string) ([]ConnInfo, error) {
+	records := make([]string, 0)
+	for _, line := range joinWrappedLines(resp, cipstatusPrefixes) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "C:") {
+			records = append(records, strings.TrimSpace(strings.TrimPrefix(line, "C:")))
+		}
+	}
+
+	conns := make([]ConnInfo, 0, len(records))
+	for _, record := range records {
+		parts := strings.Split(record, ",")
+		if len(parts) != 6 {
+			return conns, fmt.Errorf("Malformed connection status \"%s\", expecting 6 values, but got %d", record, len(parts))
+		}
+		for j := range parts {
+			parts[j] = strings.Trim(strings.TrimSpace(parts[j]), `"`)
+		}
+		var conn ConnInfo
+		var err error
+		if conn.Index, err = strconv.Atoi(parts[0]); err != nil {
+			return conns, fmt.Errorf("Malformed connection index in \"%s\": %w", record, err)
+		}
+		if parts[1] != "" {
+			if conn.Bearer, err = strconv.Atoi(parts[1]); err != nil {
+				return conns, fmt.Errorf("Malformed bearer in \"%s\": %w", record, err)
+			}
+		}
+		conn.Protocol = parts[2]
+		conn.RemoteIP = parts[3]
+		if parts[4] != "" {
+			if conn.Port, err = strconv.Atoi(parts[4]); err != nil {
+				return conns, fmt.Errorf("Malformed port in \"%s\": %w", record, err)
+			}
+		}
+		conn.State = parts[5]
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
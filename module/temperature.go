@@ -0,0 +1,52 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetTemperature returns the module temperature in degrees Celsius,
+// enabling temperature detection first if it is disabled
+func (s *sim7000e) GetTemperature() (float64, error) {
+	resp, err := s.Command("+CMTE?")
+	if err != nil {
+		return 0, fmt.Errorf("Reading temperature not supported by firmware: %w", err)
+	}
+	enabled, temperature, err := ParseCMTEResp(resp)
+	if err != nil || enabled {
+		return temperature, err
+	}
+
+	if _, err := s.Command("+CMTE=1"); err != nil {
+		return 0, fmt.Errorf("Enabling temperature detection failed: %w", err)
+	}
+	resp, err = s.Command("+CMTE?")
+	if err != nil {
+		return 0, fmt.Errorf("+CMTE? failed: %w", err)
+	}
+	_, temperature, err = ParseCMTEResp(resp)
+	return temperature, err
+}
+
+// ParseCMTEResp parses a "+CMTE: <mode>,<temperature>" response,
+// returning whether temperature detection is enabled and the temperature in degrees Celsius
+func ParseCMTEResp(resp []string) (bool, float64, error) {
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CMTE:") {
+			continue
+		}
+		params := splitParams(strings.TrimPrefix(line, "+CMTE:"))
+		if len(params) != 2 {
+			return false, 0, fmt.Errorf("Malformed response to +CMTE?: \"%s\"", resp[i])
+		}
+		temperature, err := strconv.ParseFloat(params[1], 64)
+		if err != nil {
+			return false, 0, fmt.Errorf("Malformed temperature in \"%s\": %w", resp[i], err)
+		}
+		return params[0] != "0", temperature, nil
+	}
+	return false, 0, errors.New("Response to +CMTE? did not contain +CMTE:")
+}
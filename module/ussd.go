@@ -0,0 +1,113 @@
+package module
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ussdTimeout is how long SendUSSD waits for the network to reply
+var ussdTimeout = 30 * time.Second
+
+// SendUSSD sends a USSD code, e.g. "*100#" for a balance check, and returns the network's reply
+func (s *sim7000e) SendUSSD(code string) (string, error) {
+	if strings.ContainsAny(code, "\"\r\n") {
+		return "", fmt.Errorf("Invalid USSD code \"%s\"", code)
+	}
+	replies := make(chan []string, 1)
+	err := s.modem.AddIndication("+CUSD:", func(lines []string) {
+		select {
+		case replies <- lines:
+		default:
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	defer s.modem.CancelIndication("+CUSD:")
+
+	cmd := fmt.Sprintf(`+CUSD=1,"%s",15`, code)
+	if _, err := s.Command(cmd); err != nil {
+		return "", fmt.Errorf("%s failed: %w", cmd, err)
+	}
+
+	timeout := time.NewTimer(ussdTimeout)
+	defer timeout.Stop()
+	select {
+	case lines := <-replies:
+		return ParseCUSDResp(lines)
+	case <-timeout.C:
+		return "", errors.New("No reply to USSD request")
+	}
+}
+
+// ParseCUSDResp returns the text from a `+CUSD: <m>,"<str>",<dcs>` result,
+// decoding it from hex encoded UCS2 if the data coding scheme says so
+func ParseCUSDResp(resp []string) (string, error) {
+	for i := 0; i < len(resp); i++ {
+		line := strings.TrimSpace(resp[i])
+		if !strings.HasPrefix(line, "+CUSD:") {
+			continue
+		}
+		params := splitParams(strings.TrimPrefix(line, "+CUSD:"))
+		switch params[0] {
+		case "0", "1", "2":
+		case "4":
+			return "", errors.New("USSD operation not supported")
+		case "5":
+			return "", errors.New("USSD network time out")
+		default:
+			return "", fmt.Errorf("USSD request failed with status %s", params[0])
+		}
+		if len(params) < 2 {
+			return "", nil
+		}
+		text := params[1]
+		if len(params) > 2 {
+			dcs, err := strconv.Atoi(params[2])
+			if err == nil && isUCS2DCS(dcs) {
+				if decoded, err := decodeUCS2Hex(text); err == nil {
+					return decoded, nil
+				}
+			}
+		}
+		return text, nil
+	}
+	return "", errors.New("Response did not contain +CUSD:")
+}
+
+// isUCS2DCS tells if the cell broadcast data coding scheme (3GPP TS 23.038) uses UCS2
+func isUCS2DCS(dcs int) bool {
+	switch {
+	case dcs == 0x11:
+		// UCS2 preceded by language
+		return true
+	case dcs&0xc0 == 0x40:
+		// general data coding, character set in bits 2-3
+		return (dcs>>2)&0x03 == 0x02
+	case dcs&0xf0 == 0x90:
+		// message with UDH
+		return (dcs>>2)&0x03 == 0x02
+	default:
+		return false
+	}
+}
+
+func decodeUCS2Hex(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	if len(b)%2 != 0 {
+		return "", errors.New("Odd number of bytes in UCS2 text")
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units)), nil
+}
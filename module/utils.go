@@ -1,5 +1,9 @@
 package module
 
+import (
+	"strings"
+)
+
 func maxLength(a, b string) int {
 	al := len(a)
 	bl := len(b)
@@ -9,3 +13,58 @@ func maxLength(a, b string) int {
 	}
 	return bl
 }
+
+// cipstatusPrefixes are the tokens a logical line in a +CIPSTATUS response can start with
+var cipstatusPrefixes = []string{"OK", "ERROR", "STATE:", "C:", "+"}
+
+// joinWrappedLines reassembles responses where the module has wrapped
+// a long line over several physical lines, e.g. "CONNECT" + "ED".
+// A line is joined to the previous one when it doesn't start with any of the given prefixes.
+// Empty lines are kept as is and the line following an empty line is never joined.
+func joinWrappedLines(resp []string, prefixes []string) []string {
+	startsLogicalLine := func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	joined := make([]string, 0, len(resp))
+	for _, line := range resp {
+		line = strings.TrimRight(line, "\r")
+		last := len(joined) - 1
+		if last < 0 ||
+			strings.TrimSpace(line) == "" ||
+			strings.TrimSpace(joined[last]) == "" ||
+			startsLogicalLine(line) {
+			joined = append(joined, line)
+			continue
+		}
+		joined[last] += line
+	}
+	return joined
+}
+
+// splitParams splits the parameters of a response line such as
+// `1,"IP","internet","0.0.0.0"` on commas outside of double quotes,
+// returning each parameter trimmed and unquoted
+func splitParams(s string) []string {
+	params := make([]string, 0)
+	var current strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(params, strings.TrimSpace(current.String()))
+}
@@ -0,0 +1,115 @@
+package module
+
+import (
+	"strings"
+)
+
+// Variant identifies a member of the SIM7000 family
+type Variant int8
+
+// Known variants, differing mainly in supported bands and radio access technologies
+const (
+	VariantUnknown Variant = iota
+	VariantSIM7000E
+	VariantSIM7000G
+	VariantSIM7000A
+	VariantSIM7000C
+)
+
+// ParseVariant returns the variant matching the model reported by +CGMM, e.g. "SIMCOM_SIM7000E"
+func ParseVariant(model string) Variant {
+	model = strings.ToUpper(strings.TrimSpace(model))
+	idx := strings.Index(model, "SIM7000")
+	if idx < 0 {
+		return VariantUnknown
+	}
+	switch strings.TrimPrefix(model[idx:], "SIM7000") {
+	case "E", "E-N":
+		return VariantSIM7000E
+	case "G":
+		return VariantSIM7000G
+	case "A":
+		return VariantSIM7000A
+	case "C", "C-N":
+		return VariantSIM7000C
+	default:
+		return VariantUnknown
+	}
+}
+
+func (v Variant) String() string {
+	switch v {
+	case VariantSIM7000E:
+		return "SIM7000E"
+	case VariantSIM7000G:
+		return "SIM7000G"
+	case VariantSIM7000A:
+		return "SIM7000A"
+	case VariantSIM7000C:
+		return "SIM7000C"
+	default:
+		return "unknown SIM7000"
+	}
+}
+
+// variantCommands returns the commands configuring preferred mode and bands for the variant
+func variantCommands(v Variant) []CommandResponse {
+	switch v {
+	case VariantSIM7000E:
+		// European bands, both CAT-M and NB-IoT
+		return []CommandResponse{
+			NormalCommandResponse("+CNMP=38", "OK"),
+			NormalCommandResponse("+CMNB=3", "OK"),
+			NormalCommandResponse(`+CBANDCFG="NB-IOT",3,8,20`, "OK"),
+		}
+	case VariantSIM7000G:
+		// global bands, let the module choose
+		return []CommandResponse{
+			NormalCommandResponse("+CNMP=2", "OK"),
+			NormalCommandResponse("+CMNB=3", "OK"),
+		}
+	case VariantSIM7000A:
+		// North American carriers deploy CAT-M
+		return []CommandResponse{
+			NormalCommandResponse("+CNMP=38", "OK"),
+			NormalCommandResponse("+CMNB=1", "OK"),
+		}
+	case VariantSIM7000C:
+		return []CommandResponse{
+			NormalCommandResponse("+CNMP=38", "OK"),
+			NormalCommandResponse("+CMNB=2", "OK"),
+		}
+	default:
+		return nil
+	}
+}
+
+// withVariantCommands returns a copy of script which configures the variant before running the script's own commands
+func withVariantCommands(script ChatScript, v Variant) ChatScript {
+	commands := variantCommands(v)
+	if len(commands) == 0 {
+		return script
+	}
+	script.Commands = append(commands, script.Commands...)
+	return script
+}
+
+// chatScript returns the chat script to connect with: the one in settings as it is,
+// or the default one configuring variant v
+func chatScript(settings Settings, v Variant) ChatScript {
+	if settings.ChatScript != nil {
+		return *settings.ChatScript
+	}
+	return withVariantCommands(DefaultChatScript(settings), v)
+}
+
+func (s *sim7000e) detectVariant() Variant {
+	info, err := s.GetModuleInfo()
+	if err != nil {
+		print("Failed to query module info, assuming generic SIM7000:", err)
+		return VariantUnknown
+	}
+	v := ParseVariant(info.Model)
+	printf("Detected %s (model %s, revision %s)\n", v, info.Model, info.Revision)
+	return v
+}
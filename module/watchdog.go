@@ -0,0 +1,66 @@
+package module
+
+import (
+	"strings"
+	"time"
+)
+
+// WatchdogState is the SIM and network state observed by the watchdog
+type WatchdogState struct {
+	SIMReady bool // +CPIN? reports READY
+	Attached bool // +CGATT? reports attached to packet domain service
+}
+
+func (s *sim7000e) queryWatchdogState() WatchdogState {
+	var state WatchdogState
+	if resp, err := s.Command("+CPIN?"); err == nil {
+		for _, line := range resp {
+			if strings.Contains(line, "+CPIN: READY") {
+				state.SIMReady = true
+			}
+		}
+	}
+	if resp, err := s.Command("+CGATT?"); err == nil {
+		for _, line := range resp {
+			if strings.TrimSpace(line) == "+CGATT: 1" {
+				state.Attached = true
+			}
+		}
+	}
+	return state
+}
+
+// startWatchdog checks the SIM and network state every interval
+// and calls onChange whenever it differs from the previous check, until Close is called
+func (s *sim7000e) startWatchdog(interval time.Duration, onChange func(previous, current WatchdogState)) {
+	s.watchdogStop = make(chan struct{})
+	s.watchdogDone = make(chan struct{})
+	go func() {
+		defer close(s.watchdogDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := s.queryWatchdogState()
+		for {
+			select {
+			case <-s.watchdogStop:
+				return
+			case <-ticker.C:
+			}
+			current := s.queryWatchdogState()
+			if current != previous && onChange != nil {
+				onChange(previous, current)
+			}
+			previous = current
+		}
+	}()
+}
+
+func (s *sim7000e) stopWatchdog() {
+	if s.watchdogStop == nil {
+		return
+	}
+	close(s.watchdogStop)
+	<-s.watchdogDone
+	s.watchdogStop = nil
+}
@@ -0,0 +1,87 @@
+package moduleutils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+// RecoveryLevel tells which step of HealthCheckAndRecover got the module responding again
+type RecoveryLevel int
+
+// Recovery levels, in the order they are tried
+const (
+	RecoveryNone    RecoveryLevel = iota // module responded to a plain AT
+	RecoveryReset                        // module responded after +CFUN=1,1
+	RecoveryRestart                      // module responded after being powered off with +CPOWD=1
+)
+
+func (l RecoveryLevel) String() string {
+	switch l {
+	case RecoveryNone:
+		return "none"
+	case RecoveryReset:
+		return "reset"
+	case RecoveryRestart:
+		return "restart"
+	default:
+		return "unknown"
+	}
+}
+
+// healthCheckAttempts is how many plain AT commands are tried before escalating
+const healthCheckAttempts = 3
+
+// Timing of the health check, variables so tests can shorten them
+var (
+	healthCheckTimeout = 500 * time.Millisecond
+	resetRecoveryTime  = 10 * time.Second
+	powerOffTime       = 15 * time.Second
+	restartTimeout     = 20 * time.Second
+)
+
+// HealthCheckAndRecover checks that the module responds to AT commands and,
+// if it doesn't, tries to recover it by escalating from a +CFUN=1,1 reset
+// to powering it off with +CPOWD=1 and waiting for it to come back, like Restart does.
+// Everything goes through modem, so that no other reader competes with it for the port.
+// The level which got the module responding again is returned,
+// with an error if none of them did.
+func HealthCheckAndRecover(modem *at.AT) (RecoveryLevel, error) {
+	if ping(modem) {
+		return RecoveryNone, nil
+	}
+
+	output.Println("Module not responding, resetting with +CFUN=1,1")
+	modem.Command("+CFUN=1,1", at.WithTimeout(healthCheckTimeout))
+	time.Sleep(resetRecoveryTime)
+	if ping(modem) {
+		return RecoveryReset, nil
+	}
+
+	output.Println("Module not responding after reset, restarting it")
+	// the module may power down before answering
+	PowerOff(modem)
+	time.Sleep(powerOffTime)
+	deadline := time.Now().Add(restartTimeout)
+	for {
+		if ping(modem) {
+			return RecoveryRestart, nil
+		}
+		if time.Now().After(deadline) {
+			return RecoveryRestart, errors.New("Module did not respond after restarting it")
+		}
+	}
+}
+
+// ping returns true if the module responds to a plain AT within healthCheckAttempts tries
+func ping(modem *at.AT) bool {
+	for i := 0; i < healthCheckAttempts; i++ {
+		if _, err := modem.Command("", at.WithTimeout(healthCheckTimeout)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package moduleutils
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/internal/fakemodem"
+	"github.com/LassiHeikkila/SIM7000/output"
+)
+
+func TestHealthCheckAndRecoverEscalates(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	// no answer to the probes before and after the reset, answers after the restart
+	m.Reply("", "", "", "", "", "", "", "OK")
+	output.SetWriter(ioutil.Discard)
+	defer output.SetWriter(ioutil.Discard)
+
+	oldTimeout, oldRecoveryTime := healthCheckTimeout, resetRecoveryTime
+	oldPowerOffTime, oldRestartTimeout := powerOffTime, restartTimeout
+	healthCheckTimeout = 10 * time.Millisecond
+	resetRecoveryTime = 0
+	powerOffTime = 0
+	restartTimeout = time.Second
+	defer func() {
+		healthCheckTimeout, resetRecoveryTime = oldTimeout, oldRecoveryTime
+		powerOffTime, restartTimeout = oldPowerOffTime, oldRestartTimeout
+	}()
+
+	level, err := HealthCheckAndRecover(at.New(m, at.WithTimeout(time.Second)))
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+	if level != RecoveryRestart {
+		t.Fatalf(`Got recovery level %v, wanted %v`, level, RecoveryRestart)
+	}
+	if got := strings.Join(m.Commands(), "|"); got != "|||+CFUN=1,1||||+CPOWD=1|" {
+		t.Fatalf(`Got commands %q`, m.Commands())
+	}
+}
@@ -0,0 +1,70 @@
+package moduleutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/warthog618/modem/at"
+	"github.com/warthog618/modem/serial"
+)
+
+// DefaultREPLTimeout is how long REPL waits for the module to respond to a command
+const DefaultREPLTimeout = 10 * time.Second
+
+// escapeGuardTime is the silence required before and after "+++" for the module to leave data mode
+var escapeGuardTime = time.Second
+
+// REPL opens the module on dev and issues each line read from in as an AT command,
+// writing the responses to out, until in is exhausted.
+// The "AT" prefix is added to commands which lack it,
+// and a line containing only "+++" returns the module from data mode to command mode.
+func REPL(dev string, in io.Reader, out io.Writer) error {
+	return REPLWithTimeout(dev, DefaultREPLTimeout, in, out)
+}
+
+// REPLWithTimeout is like REPL but waits at most timeout for the response to each command
+func REPLWithTimeout(dev string, timeout time.Duration, in io.Reader, out io.Writer) error {
+	p, err := serial.New(serial.WithPort(dev), serial.WithBaud(115200))
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return repl(at.New(p, at.WithTimeout(timeout)), p, timeout, in, out)
+}
+
+func repl(modem *at.AT, port io.Writer, timeout time.Duration, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "+++":
+			time.Sleep(escapeGuardTime)
+			if _, err := port.Write([]byte("+++")); err != nil {
+				return err
+			}
+			time.Sleep(escapeGuardTime)
+			fmt.Fprintln(out, "Escaped to command mode")
+			continue
+		}
+
+		cmd := line
+		if strings.HasPrefix(strings.ToUpper(cmd), "AT") {
+			cmd = cmd[2:]
+		}
+		resp, err := modem.Command(cmd, at.WithTimeout(timeout))
+		for _, r := range resp {
+			fmt.Fprintln(out, "  "+r)
+		}
+		if err != nil {
+			fmt.Fprintln(out, "ERROR:", err)
+		} else {
+			fmt.Fprintln(out, "OK")
+		}
+	}
+	return scanner.Err()
+}
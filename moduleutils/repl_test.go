@@ -0,0 +1,42 @@
+package moduleutils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warthog618/modem/at"
+
+	"github.com/LassiHeikkila/SIM7000/internal/fakemodem"
+)
+
+func TestREPL(t *testing.T) {
+	m := fakemodem.New()
+	defer m.Close()
+	m.Reply("+CSQ", "+CSQ: 20,99\nOK")
+	m.Reply("+CPIN?", "+CME ERROR: 10")
+
+	oldGuardTime := escapeGuardTime
+	escapeGuardTime = 0
+	defer func() { escapeGuardTime = oldGuardTime }()
+	in := strings.NewReader("AT+CSQ\n\n+CPIN?\n+++\nati\n")
+	var out bytes.Buffer
+	err := repl(at.New(m, at.WithTimeout(time.Second)), m, time.Second, in, &out)
+	if err != nil {
+		t.Fatalf(`Unexpected error: %v`, err)
+	}
+
+	wantCmds := []string{"+CSQ", "+CPIN?", "i"}
+	gotCmds := m.Commands()
+	if strings.Join(gotCmds, " ") != strings.Join(wantCmds, " ") {
+		t.Fatalf(`Got commands %q, wanted %q`, gotCmds, wantCmds)
+	}
+	if string(m.Raw()) != "+++" {
+		t.Fatalf(`Got raw data %q, wanted "+++"`, m.Raw())
+	}
+	want := "  +CSQ: 20,99\nOK\nERROR: CME Error: 10\nEscaped to command mode\nOK\n"
+	if out.String() != want {
+		t.Fatalf(`Got output %q, wanted %q`, out.String(), want)
+	}
+}